@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"sync"
@@ -12,6 +13,15 @@ import (
 )
 
 func main() {
+	n := flag.Int("n", 20, "并发请求数")
+	rateLimit := flag.Float64("rate", 0, "每秒请求数限制，0 表示不限制")
+	burst := flag.Int("burst", 0, "令牌桶容量，0 表示与 rate 相同")
+	maxInFlight := flag.Int("max-inflight", 0, "最大并发请求数，0 表示不限制")
+	region := flag.String("region", "", "客户端所在地域")
+	zone := flag.String("zone", "", "客户端所在可用区，设置后优先调用同可用区实例")
+	minLocalReady := flag.Int("min-local-ready", 1, "同可用区健康实例数低于该值时溢出到其他可用区")
+	flag.Parse()
+
 	// 初始化etcd客户端
 	if err := etcd.InitDefaultClient(nil); err != nil {
 		log.Fatalf("etcd 初始化失败: %v", err)
@@ -24,6 +34,22 @@ func main() {
 		log.Fatalf("Failed to create discovery: %v", err)
 	}
 
+	// 设置客户端限流，超出限制的调用返回 RESOURCE_EXHAUSTED
+	discovery.SetLimits("greater-service", etcd.LimitConfig{
+		RateLimit:   *rateLimit,
+		Burst:       *burst,
+		MaxInFlight: *maxInFlight,
+	})
+
+	// 设置本地优先路由
+	if *zone != "" {
+		discovery.SetLocality(etcd.LocalityConfig{
+			Region:        *region,
+			Zone:          *zone,
+			MinLocalReady: *minLocalReady,
+		})
+	}
+
 	// 获取服务连接
 	conn, err := discovery.GetConnection(context.Background(), "greater-service")
 	if err != nil {
@@ -33,20 +59,21 @@ func main() {
 
 	client := pb.NewGreeterClient(conn)
 
-	n := 20 // 并发请求数
 	var wg sync.WaitGroup
-	wg.Add(n)
-	for i := 0; i < n; i++ {
+	wg.Add(*n)
+	for i := 0; i < *n; i++ {
 		go func(i int) {
 			defer wg.Done()
 			c, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 			defer cancel()
+			var served etcd.Locality
+			c = etcd.WithServedLocality(c, &served)
 			resp, err := client.SayHello(c, &pb.HelloRequest{Name: fmt.Sprintf("gRPC Client %d", i)})
 			if err != nil {
 				log.Printf("调用失败: %v", err)
 				return
 			}
-			fmt.Printf("[%d] 服务端返回: %s\n", i, resp.Message)
+			fmt.Printf("[%d] 服务端返回: %s (zone: %s)\n", i, resp.Message, served)
 		}(i)
 	}
 	wg.Wait()
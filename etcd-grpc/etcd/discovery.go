@@ -3,8 +3,10 @@ package etcd
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/resolver"
 )
@@ -17,6 +19,10 @@ type ServiceDiscovery interface {
 // EtcdDiscovery 实现基于etcd的服务发现
 type EtcdDiscovery struct {
 	client *Client
+
+	mu       sync.Mutex
+	limits   map[string]LimitConfig // 代码中设置的服务限流配置
+	locality *LocalityConfig        // 调用方地域，设置后启用本地优先路由
 }
 
 // NewServiceDiscovery 创建服务发现实例
@@ -31,9 +37,26 @@ func NewServiceDiscovery(client *Client) (*EtcdDiscovery, error) {
 
 	return &EtcdDiscovery{
 		client: client,
+		limits: make(map[string]LimitConfig),
 	}, nil
 }
 
+// SetLimits 设置服务的客户端限流配置，需在 GetConnection 之前调用；
+// etcd 中 /config/<serviceName>/limits 键的 JSON 配置会覆盖该值
+func (d *EtcdDiscovery) SetLimits(serviceName string, config LimitConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.limits[serviceName] = config
+}
+
+// SetLocality 设置调用方所在地域，之后获取的连接优先路由到同可用区的实例，
+// 同可用区健康实例数低于 MinLocalReady 时溢出到其他可用区
+func (d *EtcdDiscovery) SetLocality(config LocalityConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.locality = &config
+}
+
 // GetConnection 获取服务连接
 func (d *EtcdDiscovery) GetConnection(ctx context.Context, serviceName string) (*grpc.ClientConn, error) {
 	// 注册解析器
@@ -43,15 +66,41 @@ func (d *EtcdDiscovery) GetConnection(ctx context.Context, serviceName string) (
 	}
 	resolver.Register(builder)
 
+	// 创建限流器
+	d.mu.Lock()
+	limiter := newServiceLimiter(serviceName, d.limits[serviceName])
+	serviceConfig := `{"loadBalancingPolicy":"round_robin"}`
+	if d.locality != nil {
+		serviceConfig = d.locality.serviceConfigJSON()
+	}
+	d.mu.Unlock()
+
 	// 创建连接
 	conn, err := grpc.NewClient(
 		fmt.Sprintf("etcd:///%s", serviceName),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultServiceConfig(`{"loadBalancingPolicy":"round_robin"}`),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithChainUnaryInterceptor(limiter.unaryInterceptor()),
+		grpc.WithChainStreamInterceptor(limiter.streamInterceptor()),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create gRPC connection: %w", err)
 	}
 
+	// 监听 etcd 中的限流配置，连接关闭或 etcd 客户端关闭时停止
+	watchCtx, cancel := context.WithCancel(d.client.client.Ctx())
+	go limiter.watchConfig(watchCtx, d.client.client)
+	go cancelOnShutdown(watchCtx, cancel, conn)
+
 	return conn, nil
 }
+
+// cancelOnShutdown 等待连接关闭后调用 cancel
+func cancelOnShutdown(ctx context.Context, cancel context.CancelFunc, conn *grpc.ClientConn) {
+	defer cancel()
+	for state := conn.GetState(); state != connectivity.Shutdown; state = conn.GetState() {
+		if !conn.WaitForStateChange(ctx, state) {
+			return
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LimitConfig 定义单个服务的客户端限流配置，零值表示不限制
+type LimitConfig struct {
+	RateLimit   float64 `json:"rate_limit"`    // 每秒允许的请求数（令牌桶填充速率）
+	Burst       int     `json:"burst"`         // 令牌桶容量，<=0 时取 RateLimit 向上取整
+	MaxInFlight int     `json:"max_in_flight"` // 最大并发请求数
+}
+
+// limitConfigKey 返回服务限流配置在 etcd 中的键
+func limitConfigKey(serviceName string) string {
+	return fmt.Sprintf("/config/%s/limits", serviceName)
+}
+
+// serviceLimiter 为单个服务实现令牌桶限流和并发数限制
+type serviceLimiter struct {
+	serviceName string
+	defaults    LimitConfig // 代码中设置的配置，etcd 中的配置被删除时恢复
+
+	mu       sync.Mutex
+	config   LimitConfig
+	tokens   float64
+	last     time.Time
+	inFlight int
+}
+
+// newServiceLimiter 创建服务限流器
+func newServiceLimiter(serviceName string, config LimitConfig) *serviceLimiter {
+	l := &serviceLimiter{serviceName: serviceName, defaults: config}
+	l.update(config)
+	return l
+}
+
+// update 更新限流配置。令牌桶保留当前令牌数，只在超过新容量时截断，
+// 避免每次修改配置都放行一整桶突发请求；之前未限速时令牌桶从满桶开始
+func (l *serviceLimiter) update(config LimitConfig) {
+	if config.RateLimit > 0 && config.Burst <= 0 {
+		config.Burst = int(config.RateLimit)
+		if float64(config.Burst) < config.RateLimit {
+			config.Burst++
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.config.RateLimit > 0 {
+		l.refill(now)
+	} else {
+		l.tokens = float64(config.Burst)
+	}
+	l.config = config
+	if l.tokens > float64(config.Burst) {
+		l.tokens = float64(config.Burst)
+	}
+	l.last = now
+}
+
+// refill 按当前速率补充令牌，调用方需持有锁
+func (l *serviceLimiter) refill(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.config.RateLimit
+	if l.tokens > float64(l.config.Burst) {
+		l.tokens = float64(l.config.Burst)
+	}
+	l.last = now
+}
+
+// acquire 获取一个令牌和一个并发名额，失败时返回 RESOURCE_EXHAUSTED
+func (l *serviceLimiter) acquire() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.config.MaxInFlight > 0 && l.inFlight >= l.config.MaxInFlight {
+		return status.Errorf(codes.ResourceExhausted,
+			"service %s: max in-flight requests (%d) exceeded", l.serviceName, l.config.MaxInFlight)
+	}
+
+	if l.config.RateLimit > 0 {
+		l.refill(time.Now())
+		if l.tokens < 1 {
+			return status.Errorf(codes.ResourceExhausted,
+				"service %s: rate limit (%.2f req/s, burst %d) exceeded", l.serviceName, l.config.RateLimit, l.config.Burst)
+		}
+		l.tokens--
+	}
+
+	l.inFlight++
+	return nil
+}
+
+// release 归还并发名额
+func (l *serviceLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight > 0 {
+		l.inFlight--
+	}
+}
+
+// unaryInterceptor 返回一元调用的限流拦截器
+func (l *serviceLimiter) unaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := l.acquire(); err != nil {
+			return err
+		}
+		defer l.release()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// streamInterceptor 返回流式调用的限流拦截器，流结束时归还并发名额
+func (l *serviceLimiter) streamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if err := l.acquire(); err != nil {
+			return nil, err
+		}
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			l.release()
+			return nil, err
+		}
+		go func() {
+			<-stream.Context().Done()
+			l.release()
+		}()
+		return stream, nil
+	}
+}
+
+// 限流配置监听中断后重新建立监听的退避时间
+const (
+	limitWatchMinBackoff = time.Second
+	limitWatchMaxBackoff = 30 * time.Second
+)
+
+// watchConfig 从 etcd 加载限流配置并监听变化，键不存在或被删除时使用代码中的配置。
+// 监听通道关闭（如 etcd 连接中断或修订版本被压缩）时重新读取配置并以指数退避重新监听，
+// 直到 ctx 取消
+func (l *serviceLimiter) watchConfig(ctx context.Context, client *clientv3.Client) {
+	key := limitConfigKey(l.serviceName)
+	backoff := limitWatchMinBackoff
+
+	for {
+		if rev, err := l.load(ctx, client, key); err != nil {
+			log.Printf("Limiter failed to get config for %s: %v", l.serviceName, err)
+		} else {
+			backoff = limitWatchMinBackoff
+			for wresp := range client.Watch(ctx, key, clientv3.WithRev(rev+1)) {
+				if err := wresp.Err(); err != nil {
+					log.Printf("Limiter watch for %s interrupted: %v", l.serviceName, err)
+					break
+				}
+				for _, ev := range wresp.Events {
+					l.handle(ev)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		log.Printf("Limiter re-watching config for %s", l.serviceName)
+		backoff = min(backoff*2, limitWatchMaxBackoff)
+	}
+}
+
+// load 读取 etcd 中的限流配置并返回读取时的修订版本，键不存在时恢复代码中的配置
+func (l *serviceLimiter) load(ctx context.Context, client *clientv3.Client, key string) (int64, error) {
+	resp, err := client.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Kvs) > 0 {
+		l.apply(resp.Kvs[0].Value)
+	} else {
+		l.update(l.defaults)
+	}
+	return resp.Header.Revision, nil
+}
+
+// handle 处理限流配置键的变化事件
+func (l *serviceLimiter) handle(ev *clientv3.Event) {
+	switch ev.Type {
+	case clientv3.EventTypePut:
+		l.apply(ev.Kv.Value)
+	case clientv3.EventTypeDelete:
+		l.update(l.defaults)
+		log.Printf("Limiter config deleted for %s, restored defaults: %+v", l.serviceName, l.defaults)
+	}
+}
+
+// apply 解析并应用 etcd 中的 JSON 限流配置
+func (l *serviceLimiter) apply(value []byte) {
+	var config LimitConfig
+	if err := json.Unmarshal(value, &config); err != nil {
+		log.Printf("Limiter got invalid config for %s: %v", l.serviceName, err)
+		return
+	}
+	l.update(config)
+	log.Printf("Limiter config updated for %s: %+v", l.serviceName, config)
+}
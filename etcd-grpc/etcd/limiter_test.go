@@ -0,0 +1,155 @@
+package etcd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// elapse 让令牌桶的时间倒退 d，模拟经过了 d
+func (l *serviceLimiter) elapse(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.last = l.last.Add(-d)
+}
+
+// acquireN 连续获取 n 次，返回成功的次数
+func acquireN(l *serviceLimiter, n int) int {
+	ok := 0
+	for range n {
+		if l.acquire() == nil {
+			ok++
+		}
+	}
+	return ok
+}
+
+func TestLimiterTokenBucket(t *testing.T) {
+	l := newServiceLimiter("svc", LimitConfig{RateLimit: 10, Burst: 5})
+	if got := acquireN(l, 10); got != 5 {
+		t.Fatalf("满桶时放行 %d 个请求，期望 5 个", got)
+	}
+	if err := l.acquire(); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("令牌耗尽时返回 %v，期望 ResourceExhausted", err)
+	}
+
+	// 每秒补充 10 个令牌，不超过容量
+	l.elapse(300 * time.Millisecond)
+	if got := acquireN(l, 10); got != 3 {
+		t.Errorf("300ms 后放行 %d 个请求，期望 3 个", got)
+	}
+	l.elapse(time.Hour)
+	if got := acquireN(l, 10); got != 5 {
+		t.Errorf("长时间空闲后放行 %d 个请求，期望容量 5 个", got)
+	}
+}
+
+// 未设置容量时取速率向上取整
+func TestLimiterDefaultBurst(t *testing.T) {
+	l := newServiceLimiter("svc", LimitConfig{RateLimit: 2.5})
+	if got := acquireN(l, 10); got != 3 {
+		t.Errorf("放行 %d 个请求，期望 3 个", got)
+	}
+}
+
+// 更新配置保留当前令牌数，只在超过新容量时截断
+func TestLimiterUpdateKeepsTokens(t *testing.T) {
+	l := newServiceLimiter("svc", LimitConfig{RateLimit: 1, Burst: 10})
+	if got := acquireN(l, 8); got != 8 {
+		t.Fatalf("放行 %d 个请求，期望 8 个", got)
+	}
+	l.update(LimitConfig{RateLimit: 1, Burst: 20})
+	if got := acquireN(l, 10); got != 2 {
+		t.Errorf("扩容后放行 %d 个请求，期望剩余的 2 个", got)
+	}
+
+	l = newServiceLimiter("svc", LimitConfig{RateLimit: 1, Burst: 10})
+	l.update(LimitConfig{RateLimit: 1, Burst: 4})
+	if got := acquireN(l, 10); got != 4 {
+		t.Errorf("缩容后放行 %d 个请求，期望新容量 4 个", got)
+	}
+
+	// 之前未限速时从满桶开始
+	l = newServiceLimiter("svc", LimitConfig{})
+	l.update(LimitConfig{RateLimit: 1, Burst: 3})
+	if got := acquireN(l, 10); got != 3 {
+		t.Errorf("开启限速后放行 %d 个请求，期望 3 个", got)
+	}
+}
+
+func TestLimiterMaxInFlight(t *testing.T) {
+	l := newServiceLimiter("svc", LimitConfig{MaxInFlight: 2})
+	if got := acquireN(l, 3); got != 2 {
+		t.Fatalf("放行 %d 个并发请求，期望 2 个", got)
+	}
+	if err := l.acquire(); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("并发数已满时返回 %v，期望 ResourceExhausted", err)
+	}
+	l.release()
+	if err := l.acquire(); err != nil {
+		t.Fatalf("归还名额后获取失败: %v", err)
+	}
+}
+
+// 拦截器在超出限制时不发起调用并返回 RESOURCE_EXHAUSTED，调用结束后归还名额
+func TestLimiterUnaryInterceptor(t *testing.T) {
+	l := newServiceLimiter("svc", LimitConfig{MaxInFlight: 1})
+	intercept := l.unaryInterceptor()
+
+	calls := 0
+	var nested error
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		// 调用进行中再次调用超出并发数
+		nested = intercept(ctx, method, req, reply, cc, func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+			calls++
+			return nil
+		})
+		return nil
+	}
+	if err := intercept(context.Background(), "/svc/Method", nil, nil, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+	if status.Code(nested) != codes.ResourceExhausted {
+		t.Errorf("超出并发数的调用返回 %v，期望 ResourceExhausted", nested)
+	}
+	if calls != 1 {
+		t.Errorf("发起了 %d 次调用，期望 1 次", calls)
+	}
+	if err := intercept(context.Background(), "/svc/Method", nil, nil, nil, invoker); err != nil {
+		t.Errorf("调用结束后没有归还并发名额: %v", err)
+	}
+}
+
+// etcd 中的配置覆盖代码中的配置，键被删除后恢复代码中的配置
+func TestLimiterConfigRestore(t *testing.T) {
+	defaults := LimitConfig{MaxInFlight: 1}
+	l := newServiceLimiter("svc", defaults)
+
+	l.apply([]byte(`{"max_in_flight":3}`))
+	if got := acquireN(l, 5); got != 3 {
+		t.Fatalf("etcd 配置生效后放行 %d 个并发请求，期望 3 个", got)
+	}
+	for range 3 {
+		l.release()
+	}
+
+	// 无效的配置被忽略
+	l.apply([]byte(`{"max_in_flight":"x"}`))
+	if got := acquireN(l, 5); got != 3 {
+		t.Fatalf("无效配置后放行 %d 个并发请求，期望 3 个", got)
+	}
+	for range 3 {
+		l.release()
+	}
+
+	l.handle(&clientv3.Event{Type: clientv3.EventTypeDelete})
+	if got := acquireN(l, 5); got != 1 {
+		t.Errorf("配置删除后放行 %d 个并发请求，期望恢复为 1 个", got)
+	}
+}
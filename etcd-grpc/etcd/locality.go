@@ -0,0 +1,226 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/serviceconfig"
+)
+
+// LocalityBalancerName 本地优先负载均衡策略名称
+const LocalityBalancerName = "locality_aware"
+
+// Locality 描述服务实例或调用方所在的地域和可用区
+type Locality struct {
+	Region string `json:"region,omitempty"` // 地域
+	Zone   string `json:"zone,omitempty"`   // 可用区
+}
+
+// String 返回 region/zone 形式的描述
+func (l Locality) String() string {
+	if l.Region == "" && l.Zone == "" {
+		return "unknown"
+	}
+	return l.Region + "/" + l.Zone
+}
+
+// LocalityConfig 定义调用方的本地优先路由配置
+type LocalityConfig struct {
+	Region string `json:"region"` // 调用方所在地域，为空时只按可用区名称匹配
+	Zone   string `json:"zone"`   // 调用方所在可用区
+	// MinLocalReady 本可用区健康实例数低于该阈值时溢出到同地域其他可用区，
+	// 仍不足时溢出到所有实例，<=0 时取 1
+	MinLocalReady int `json:"min_local_ready"`
+}
+
+// tier 返回实例相对调用方的优先级：0 为同可用区，1 为同地域，2 为其他实例。
+// 调用方未设置地域时只按可用区名称匹配，同地域一层为空
+func (c LocalityConfig) tier(l Locality) int {
+	switch {
+	case c.Zone != "" && l.Zone == c.Zone && (c.Region == "" || l.Region == c.Region):
+		return 0
+	case c.Region != "" && l.Region == c.Region:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// serviceConfigJSON 返回使用本地优先策略的 gRPC 服务配置
+func (c LocalityConfig) serviceConfigJSON() string {
+	cfg, _ := json.Marshal(c)
+	return fmt.Sprintf(`{"loadBalancingConfig":[{%q:%s}]}`, LocalityBalancerName, cfg)
+}
+
+// instanceInfo 是注册到 etcd 的服务实例信息，未设置地域时只写入地址字符串
+type instanceInfo struct {
+	Addr     string   `json:"addr"`
+	Locality Locality `json:"locality"`
+}
+
+// parseInstanceInfo 解析 etcd 中的服务实例信息，兼容纯地址格式
+func parseInstanceInfo(value []byte) instanceInfo {
+	var info instanceInfo
+	if len(value) > 0 && value[0] == '{' {
+		if err := json.Unmarshal(value, &info); err == nil {
+			return info
+		}
+	}
+	return instanceInfo{Addr: string(value)}
+}
+
+type localityAttrKey struct{}
+
+// withLocality 将实例的地域信息附加到地址上，供负载均衡器使用
+func withLocality(addr resolver.Address, locality Locality) resolver.Address {
+	addr.BalancerAttributes = addr.BalancerAttributes.WithValue(localityAttrKey{}, locality)
+	return addr
+}
+
+// localityOf 读取地址上的地域信息
+func localityOf(addr resolver.Address) Locality {
+	locality, _ := addr.BalancerAttributes.Value(localityAttrKey{}).(Locality)
+	return locality
+}
+
+type servedLocalityKey struct{}
+
+// WithServedLocality 返回一个上下文，使用该上下文发起调用后，
+// 负载均衡器会把实际处理请求的实例地域写入 locality
+func WithServedLocality(ctx context.Context, locality *Locality) context.Context {
+	return context.WithValue(ctx, servedLocalityKey{}, locality)
+}
+
+func init() {
+	balancer.Register(localityBalancerBuilder{})
+}
+
+// localityBalancerBuilder 构建本地优先负载均衡器
+type localityBalancerBuilder struct{}
+
+// Build 构建负载均衡器，连接管理复用 base 负载均衡器
+func (localityBalancerBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pb := &localityPickerBuilder{}
+	return &localityBalancer{
+		Balancer:      base.NewBalancerBuilder(LocalityBalancerName, pb, base.Config{HealthCheck: true}).Build(cc, opts),
+		pickerBuilder: pb,
+	}
+}
+
+// Name 返回负载均衡策略名称
+func (localityBalancerBuilder) Name() string {
+	return LocalityBalancerName
+}
+
+// ParseConfig 解析服务配置中的 LocalityConfig
+func (localityBalancerBuilder) ParseConfig(js json.RawMessage) (serviceconfig.LoadBalancingConfig, error) {
+	cfg := &localityLBConfig{}
+	if err := json.Unmarshal(js, &cfg.LocalityConfig); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", LocalityBalancerName, err)
+	}
+	if cfg.MinLocalReady <= 0 {
+		cfg.MinLocalReady = 1
+	}
+	return cfg, nil
+}
+
+type localityLBConfig struct {
+	serviceconfig.LoadBalancingConfig
+	LocalityConfig
+}
+
+// localityBalancer 在 base 负载均衡器基础上记录调用方地域配置
+type localityBalancer struct {
+	balancer.Balancer
+	pickerBuilder *localityPickerBuilder
+}
+
+// UpdateClientConnState 先更新配置再交给 base 负载均衡器重建 Picker
+func (b *localityBalancer) UpdateClientConnState(s balancer.ClientConnState) error {
+	if cfg, ok := s.BalancerConfig.(*localityLBConfig); ok {
+		b.pickerBuilder.setConfig(cfg.LocalityConfig)
+	}
+	return b.Balancer.UpdateClientConnState(s)
+}
+
+// localityPickerBuilder 按调用方地域对健康实例分层并构建 Picker
+type localityPickerBuilder struct {
+	mu     sync.Mutex
+	config LocalityConfig
+}
+
+func (pb *localityPickerBuilder) setConfig(config LocalityConfig) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.config = config
+}
+
+// Build 选出候选实例：同可用区优先，数量不足阈值时依次加入同地域和其他地域的实例
+func (pb *localityPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	pb.mu.Lock()
+	config := pb.config
+	pb.mu.Unlock()
+
+	var zone, region, others []localitySubConn
+	for sc, sci := range info.ReadySCs {
+		l := localityOf(sci.Address)
+		entry := localitySubConn{sc: sc, locality: l}
+		switch config.tier(l) {
+		case 0:
+			zone = append(zone, entry)
+		case 1:
+			region = append(region, entry)
+		default:
+			others = append(others, entry)
+		}
+	}
+
+	candidates := zone
+	if len(candidates) < config.MinLocalReady {
+		candidates = append(candidates, region...)
+	}
+	if len(candidates) < config.MinLocalReady {
+		candidates = append(candidates, others...)
+	}
+	if len(candidates) == 0 {
+		candidates = append(append(zone, region...), others...)
+	}
+
+	if config.Zone != "" && len(zone) < len(candidates) {
+		log.Printf("Locality balancer: %d ready instances in zone %s, spilling over to %d instances",
+			len(zone), Locality{Region: config.Region, Zone: config.Zone}, len(candidates))
+	}
+	return &localityPicker{subConns: candidates}
+}
+
+type localitySubConn struct {
+	sc       balancer.SubConn
+	locality Locality
+}
+
+// localityPicker 在候选实例间轮询
+type localityPicker struct {
+	subConns []localitySubConn
+	next     atomic.Uint32
+}
+
+// Pick 选择实例，并在调用方需要时报告实例所在地域
+func (p *localityPicker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	n := p.next.Add(1)
+	picked := p.subConns[n%uint32(len(p.subConns))]
+	if served, ok := info.Ctx.Value(servedLocalityKey{}).(*Locality); ok && served != nil {
+		*served = picked.locality
+	}
+	return balancer.PickResult{SubConn: picked.sc}, nil
+}
@@ -0,0 +1,150 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+)
+
+// fakeSubConn 以实例名称标识的 SubConn
+type fakeSubConn struct {
+	balancer.SubConn
+	name string
+}
+
+// testInstances 按名称给出实例所在的地域和可用区
+var testInstances = map[string]Locality{
+	"a1": {Region: "r1", Zone: "a"},
+	"a2": {Region: "r1", Zone: "a"},
+	"b1": {Region: "r1", Zone: "b"},
+	"c1": {Region: "r2", Zone: "c"},
+	"x1": {Region: "r2", Zone: "a"}, // 与 r1/a 可用区同名的其他地域实例
+	"u1": {},                        // 未设置地域的实例
+}
+
+// buildPicker 用 names 对应的健康实例构建 Picker，返回候选实例名称
+func buildPicker(t *testing.T, config LocalityConfig, names ...string) []string {
+	t.Helper()
+	info := base.PickerBuildInfo{ReadySCs: make(map[balancer.SubConn]base.SubConnInfo)}
+	for _, name := range names {
+		addr := withLocality(resolver.Address{Addr: name}, testInstances[name])
+		info.ReadySCs[&fakeSubConn{name: name}] = base.SubConnInfo{Address: addr}
+	}
+	pb := &localityPickerBuilder{}
+	pb.setConfig(config)
+	picker, ok := pb.Build(info).(*localityPicker)
+	if !ok {
+		t.Fatalf("没有构建出 localityPicker")
+	}
+	var got []string
+	for _, sc := range picker.subConns {
+		got = append(got, sc.sc.(*fakeSubConn).name)
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestLocalityPickerTiers(t *testing.T) {
+	all := []string{"a1", "a2", "b1", "c1", "x1", "u1"}
+	tests := []struct {
+		name   string
+		config LocalityConfig
+		ready  []string
+		want   []string
+	}{
+		{"同可用区优先", LocalityConfig{Region: "r1", Zone: "a", MinLocalReady: 1}, all, []string{"a1", "a2"}},
+		{"同可用区满足阈值", LocalityConfig{Region: "r1", Zone: "a", MinLocalReady: 2}, all, []string{"a1", "a2"}},
+		{"同可用区不足时加入同地域", LocalityConfig{Region: "r1", Zone: "a", MinLocalReady: 3}, all, []string{"a1", "a2", "b1"}},
+		{"同地域仍不足时加入所有实例", LocalityConfig{Region: "r1", Zone: "a", MinLocalReady: 4}, all, all},
+		{"同可用区没有实例", LocalityConfig{Region: "r1", Zone: "a", MinLocalReady: 1}, []string{"b1", "c1"}, []string{"b1"}},
+		{"同地域没有实例", LocalityConfig{Region: "r1", Zone: "a", MinLocalReady: 1}, []string{"c1", "u1"}, []string{"c1", "u1"}},
+		{"只设置地域", LocalityConfig{Region: "r2", MinLocalReady: 1}, all, []string{"c1", "x1"}},
+		{"只设置可用区时按名称匹配", LocalityConfig{Zone: "a", MinLocalReady: 1}, all, []string{"a1", "a2", "x1"}},
+		{"只设置可用区且不足时加入所有实例", LocalityConfig{Zone: "a", MinLocalReady: 4}, all, all},
+		{"未设置地域", LocalityConfig{MinLocalReady: 1}, all, all},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildPicker(t, tt.config, tt.ready...)
+			want := append([]string(nil), tt.want...)
+			sort.Strings(want)
+			if len(got) != len(want) {
+				t.Fatalf("候选实例为 %v，期望 %v", got, want)
+			}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Fatalf("候选实例为 %v，期望 %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+// 没有健康实例时返回 ErrNoSubConnAvailable
+func TestLocalityPickerNoReady(t *testing.T) {
+	pb := &localityPickerBuilder{}
+	_, err := pb.Build(base.PickerBuildInfo{}).Pick(balancer.PickInfo{Ctx: context.Background()})
+	if err != balancer.ErrNoSubConnAvailable {
+		t.Fatalf("Pick 返回 %v，期望 ErrNoSubConnAvailable", err)
+	}
+}
+
+// Picker 在候选实例间轮询，并报告实际处理请求的实例地域
+func TestLocalityPickerPick(t *testing.T) {
+	a1 := &fakeSubConn{name: "a1"}
+	a2 := &fakeSubConn{name: "a2"}
+	picker := &localityPicker{subConns: []localitySubConn{
+		{sc: a1, locality: testInstances["a1"]},
+		{sc: a2, locality: testInstances["a2"]},
+	}}
+	seen := make(map[balancer.SubConn]int)
+	for range 4 {
+		var served Locality
+		res, err := picker.Pick(balancer.PickInfo{Ctx: WithServedLocality(context.Background(), &served)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[res.SubConn]++
+		if served != (Locality{Region: "r1", Zone: "a"}) {
+			t.Errorf("报告的实例地域为 %s，期望 r1/a", served)
+		}
+	}
+	if seen[a1] != 2 || seen[a2] != 2 {
+		t.Errorf("轮询结果为 a1=%d a2=%d，期望各 2 次", seen[a1], seen[a2])
+	}
+}
+
+func TestLocalityParseConfig(t *testing.T) {
+	cfg := LocalityConfig{Region: "r1", Zone: "a", MinLocalReady: 3}
+	js, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(js) != `{"region":"r1","zone":"a","min_local_ready":3}` {
+		t.Errorf("配置序列化为 %s", js)
+	}
+	parsed, err := localityBalancerBuilder{}.ParseConfig(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.(*localityLBConfig).LocalityConfig; got != cfg {
+		t.Errorf("解析出的配置为 %+v，期望 %+v", got, cfg)
+	}
+
+	// 未设置阈值时取 1
+	parsed, err = localityBalancerBuilder{}.ParseConfig(json.RawMessage(`{"zone":"a"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.(*localityLBConfig).MinLocalReady; got != 1 {
+		t.Errorf("默认阈值为 %d，期望 1", got)
+	}
+	if _, err := (localityBalancerBuilder{}).ParseConfig(json.RawMessage(`{"zone":1}`)); err == nil {
+		t.Error("无效的配置应解析失败")
+	}
+}
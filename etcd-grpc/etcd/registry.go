@@ -2,6 +2,7 @@ package etcd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 
@@ -14,6 +15,14 @@ type ServiceRegistry interface {
 	Deregister(ctx context.Context, serviceName, instanceID string) error
 }
 
+// LocalityRegistry 支持记录实例地域的服务注册接口
+type LocalityRegistry interface {
+	ServiceRegistry
+	RegisterWithLocality(ctx context.Context, serviceName, instanceID, addr string, locality Locality) error
+}
+
+var _ LocalityRegistry = (*EtcdRegistry)(nil)
+
 // EtcdRegistry 实现基于etcd的服务注册
 type EtcdRegistry struct {
 	client *Client
@@ -36,6 +45,21 @@ func NewServiceRegistry(client *Client) (*EtcdRegistry, error) {
 
 // Register 注册服务
 func (r *EtcdRegistry) Register(ctx context.Context, serviceName, instanceID, addr string) error {
+	return r.RegisterWithLocality(ctx, serviceName, instanceID, addr, Locality{})
+}
+
+// RegisterWithLocality 注册服务并记录实例所在的地域和可用区
+func (r *EtcdRegistry) RegisterWithLocality(ctx context.Context, serviceName, instanceID, addr string, locality Locality) error {
+	// 未设置地域时保持纯地址格式，兼容旧版解析器
+	value := addr
+	if locality != (Locality{}) {
+		data, err := json.Marshal(instanceInfo{Addr: addr, Locality: locality})
+		if err != nil {
+			return fmt.Errorf("failed to encode instance info: %w", err)
+		}
+		value = string(data)
+	}
+
 	// 创建租约
 	lease, err := r.client.client.Grant(ctx, 5)
 	if err != nil {
@@ -67,12 +91,12 @@ func (r *EtcdRegistry) Register(ctx context.Context, serviceName, instanceID, ad
 
 	// 写入服务信息
 	key := fmt.Sprintf("/services/%s/%s", serviceName, instanceID)
-	_, err = r.client.client.Put(ctx, key, addr, clientv3.WithLease(lease.ID))
+	_, err = r.client.client.Put(ctx, key, value, clientv3.WithLease(lease.ID))
 	if err != nil {
 		return fmt.Errorf("failed to register service: %w", err)
 	}
 
-	log.Printf("Service registered successfully: %s/%s at %s", serviceName, instanceID, addr)
+	log.Printf("Service registered successfully: %s/%s at %s (%s)", serviceName, instanceID, addr, locality)
 	return nil
 }
 
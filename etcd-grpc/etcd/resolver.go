@@ -72,7 +72,8 @@ func (r *EtcdResolver) watch() {
 		// 更新地址列表
 		var addresses []resolver.Address
 		for _, kv := range resp.Kvs {
-			addresses = append(addresses, resolver.Address{Addr: string(kv.Value)})
+			info := parseInstanceInfo(kv.Value)
+			addresses = append(addresses, withLocality(resolver.Address{Addr: info.Addr}, info.Locality))
 		}
 
 		err = r.cc.UpdateState(resolver.State{Addresses: addresses})
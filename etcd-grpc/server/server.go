@@ -29,6 +29,8 @@ var _ pb.GreeterServer = (*server)(nil)
 
 func main() {
 	port := flag.String("port", "1234", "服务端口")
+	region := flag.String("region", "", "实例所在地域")
+	zone := flag.String("zone", "", "实例所在可用区")
 	flag.Parse()
 	addr := ":" + *port
 
@@ -52,7 +54,8 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	err = registry.Register(ctx, "greater-service", "instance-"+*port, "localhost:"+*port)
+	locality := etcd.Locality{Region: *region, Zone: *zone}
+	err = registry.RegisterWithLocality(ctx, "greater-service", "instance-"+*port, "localhost:"+*port, locality)
 	if err != nil {
 		log.Fatalf("Failed to register service: %v", err)
 	}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"pubsub/auth"
+	pb "pubsub/proto/pubsub"
+)
+
+// usage 打印命令用法
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: admin [-addr=<服务地址>] <命令> [参数]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "命令:")
+	fmt.Fprintln(os.Stderr, "  list [模式]       列出主题，可按 glob 模式过滤，如 orders.*")
+	fmt.Fprintln(os.Stderr, "  stats <主题>...   查看主题的订阅者数量、发布速率和最后一条消息时间")
+	fmt.Fprintln(os.Stderr, "  delete <主题>     删除持久化主题及其消费者组")
+	fmt.Fprintln(os.Stderr, "  redrive <死信主题> [消息ID...]  将死信发布回原主题，不指定消息 ID 时重新投递全部死信")
+	fmt.Fprintln(os.Stderr, "  clear-retained <主题>...  清除主题的保留消息")
+	fmt.Fprintln(os.Stderr, "  create-push <名称> <主题> <地址> [过滤表达式]  创建推送订阅，打印签名密钥")
+	fmt.Fprintln(os.Stderr, "  delete-push <名称>...  删除推送订阅")
+	fmt.Fprintln(os.Stderr, "  list-push         列出推送订阅")
+	fmt.Fprintln(os.Stderr, "")
+	flag.PrintDefaults()
+}
+
+// listTopics 列出主题
+func listTopics(ctx context.Context, client pb.PubSubClient, args []string) error {
+	req := &pb.ListTopicsRequest{}
+	if len(args) > 0 {
+		req.Pattern = args[0]
+	}
+	resp, err := client.ListTopics(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, t := range resp.Topics {
+		fmt.Println(t)
+	}
+	return nil
+}
+
+// topicStats 打印主题统计
+func topicStats(ctx context.Context, client pb.PubSubClient, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("必须指定主题")
+	}
+	for _, topic := range args {
+		stats, err := client.GetTopicStats(ctx, &pb.GetTopicStatsRequest{Topic: topic})
+		if err != nil {
+			return err
+		}
+		lastMessage := "无"
+		if stats.LastMessageTime != nil {
+			lastMessage = stats.LastMessageTime.AsTime().Local().Format(time.RFC3339)
+		}
+		fmt.Printf("主题: %s\n", stats.Topic)
+		fmt.Printf("  本地订阅者: %d\n", stats.LocalSubscribers)
+		fmt.Printf("  Redis 订阅数: %d\n", stats.RedisSubscribers)
+		fmt.Printf("  已发布消息: %d\n", stats.PublishedCount)
+		fmt.Printf("  发布速率: %.2f 条/秒\n", stats.PublishRate)
+		fmt.Printf("  最后一条消息: %s\n", lastMessage)
+		if stats.StreamLength > 0 {
+			fmt.Printf("  Stream 长度: %d\n", stats.StreamLength)
+		}
+	}
+	return nil
+}
+
+// deleteTopic 删除持久化主题
+func deleteTopic(ctx context.Context, client pb.PubSubClient, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("必须指定一个主题")
+	}
+	resp, err := client.DeleteTopic(ctx, &pb.DeleteTopicRequest{Topic: args[0]})
+	if err != nil {
+		return err
+	}
+	if resp.Deleted {
+		fmt.Printf("已删除主题 %s\n", args[0])
+	}
+	return nil
+}
+
+// redrive 将死信发布回原主题
+func redrive(ctx context.Context, client pb.PubSubClient, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("必须指定死信主题")
+	}
+	resp, err := client.Redrive(ctx, &pb.RedriveRequest{DeadLetterTopic: args[0], Ids: args[1:]})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已将 %d 条死信发布回原主题\n", resp.RedrivenCount)
+	return nil
+}
+
+// clearRetained 清除主题的保留消息
+func clearRetained(ctx context.Context, client pb.PubSubClient, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("必须指定主题")
+	}
+	for _, topic := range args {
+		resp, err := client.ClearRetained(ctx, &pb.ClearRetainedRequest{Topic: topic})
+		if err != nil {
+			return err
+		}
+		if resp.Cleared {
+			fmt.Printf("已清除主题 %s 的保留消息\n", topic)
+		} else {
+			fmt.Printf("主题 %s 没有保留消息\n", topic)
+		}
+	}
+	return nil
+}
+
+// createPush 创建推送订阅，签名密钥只在创建时返回
+func createPush(ctx context.Context, client pb.PubSubClient, args []string) error {
+	if len(args) != 3 && len(args) != 4 {
+		return fmt.Errorf("必须指定名称、主题和推送地址")
+	}
+	sub := &pb.PushSubscription{Name: args[0], Topic: args[1], Endpoint: args[2]}
+	if len(args) == 4 {
+		sub.Filter = args[3]
+	}
+	sub, err := client.CreatePushSubscription(ctx, &pb.CreatePushSubscriptionRequest{Subscription: sub})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已创建推送订阅 %s: 主题 %s -> %s\n", sub.Name, sub.Topic, sub.Endpoint)
+	fmt.Printf("签名密钥: %s\n", sub.Secret)
+	return nil
+}
+
+// deletePush 删除推送订阅
+func deletePush(ctx context.Context, client pb.PubSubClient, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("必须指定推送订阅名称")
+	}
+	for _, name := range args {
+		resp, err := client.DeletePushSubscription(ctx, &pb.DeletePushSubscriptionRequest{Name: name})
+		if err != nil {
+			return err
+		}
+		if resp.Deleted {
+			fmt.Printf("已删除推送订阅 %s\n", name)
+		} else {
+			fmt.Printf("推送订阅 %s 不存在\n", name)
+		}
+	}
+	return nil
+}
+
+// listPush 列出推送订阅
+func listPush(ctx context.Context, client pb.PubSubClient, args []string) error {
+	resp, err := client.ListPushSubscriptions(ctx, &pb.ListPushSubscriptionsRequest{})
+	if err != nil {
+		return err
+	}
+	for _, sub := range resp.Subscriptions {
+		fmt.Printf("%s\t%s\t%s", sub.Name, sub.Topic, sub.Endpoint)
+		if sub.Filter != "" {
+			fmt.Printf("\t%s", sub.Filter)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:1234", "PubSub 服务地址")
+	timeout := flag.Duration("timeout", 10*time.Second, "请求超时时间")
+	token := flag.String("token", os.Getenv("PUBSUB_TOKEN"), "访问令牌，服务端启用 ACL 时需要，默认读取环境变量 PUBSUB_TOKEN")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	commands := map[string]func(context.Context, pb.PubSubClient, []string) error{
+		"list":           listTopics,
+		"stats":          topicStats,
+		"delete":         deleteTopic,
+		"redrive":        redrive,
+		"clear-retained": clearRetained,
+		"create-push":    createPush,
+		"delete-push":    deletePush,
+		"list-push":      listPush,
+	}
+	command, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if *token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(auth.TokenCredentials(*token)))
+	}
+	conn, err := grpc.NewClient(*addr, dialOpts...)
+	if err != nil {
+		log.Fatalf("连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := command(ctx, pb.NewPubSubClient(conn), flag.Args()[1:]); err != nil {
+		log.Fatalf("%s 失败: %v", flag.Arg(0), err)
+	}
+}
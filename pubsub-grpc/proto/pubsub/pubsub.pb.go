@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.4
+// 	protoc-gen-go v1.36.6
 // 	protoc        v5.29.3
 // source: pubsub.proto
 
@@ -9,6 +9,7 @@ package pubsub
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -21,29 +22,149 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// 发布消息请求
-type PublishRequest struct {
+// 订阅的起始位置
+type StartPosition int32
+
+const (
+	StartPosition_START_LATEST    StartPosition = 0 // 只接收订阅之后发布的消息
+	StartPosition_START_EARLIEST  StartPosition = 1 // 从保留的最早消息开始
+	StartPosition_START_AFTER_ID  StartPosition = 2 // 从 start_id 之后的消息开始，用于断点续订
+	StartPosition_START_FROM_TIME StartPosition = 3 // 从 start_time 及之后发布的消息开始
+	StartPosition_START_LAST_N    StartPosition = 4 // 从最近的 last_n 条消息开始
+)
+
+// Enum value maps for StartPosition.
+var (
+	StartPosition_name = map[int32]string{
+		0: "START_LATEST",
+		1: "START_EARLIEST",
+		2: "START_AFTER_ID",
+		3: "START_FROM_TIME",
+		4: "START_LAST_N",
+	}
+	StartPosition_value = map[string]int32{
+		"START_LATEST":    0,
+		"START_EARLIEST":  1,
+		"START_AFTER_ID":  2,
+		"START_FROM_TIME": 3,
+		"START_LAST_N":    4,
+	}
+)
+
+func (x StartPosition) Enum() *StartPosition {
+	p := new(StartPosition)
+	*p = x
+	return p
+}
+
+func (x StartPosition) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (StartPosition) Descriptor() protoreflect.EnumDescriptor {
+	return file_pubsub_proto_enumTypes[0].Descriptor()
+}
+
+func (StartPosition) Type() protoreflect.EnumType {
+	return &file_pubsub_proto_enumTypes[0]
+}
+
+func (x StartPosition) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use StartPosition.Descriptor instead.
+func (StartPosition) EnumDescriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{0}
+}
+
+// 订阅者缓冲区满时的处理策略
+type OverflowPolicy int32
+
+const (
+	OverflowPolicy_OVERFLOW_DEFAULT     OverflowPolicy = 0 // 使用服务端默认策略
+	OverflowPolicy_OVERFLOW_BLOCK       OverflowPolicy = 1 // 阻塞，等待订阅者处理
+	OverflowPolicy_OVERFLOW_DROP_OLDEST OverflowPolicy = 2 // 丢弃缓冲区中最旧的消息
+	OverflowPolicy_OVERFLOW_DROP_NEWEST OverflowPolicy = 3 // 丢弃新到达的消息
+	OverflowPolicy_OVERFLOW_DISCONNECT  OverflowPolicy = 4 // 断开订阅，返回 RESOURCE_EXHAUSTED
+)
+
+// Enum value maps for OverflowPolicy.
+var (
+	OverflowPolicy_name = map[int32]string{
+		0: "OVERFLOW_DEFAULT",
+		1: "OVERFLOW_BLOCK",
+		2: "OVERFLOW_DROP_OLDEST",
+		3: "OVERFLOW_DROP_NEWEST",
+		4: "OVERFLOW_DISCONNECT",
+	}
+	OverflowPolicy_value = map[string]int32{
+		"OVERFLOW_DEFAULT":     0,
+		"OVERFLOW_BLOCK":       1,
+		"OVERFLOW_DROP_OLDEST": 2,
+		"OVERFLOW_DROP_NEWEST": 3,
+		"OVERFLOW_DISCONNECT":  4,
+	}
+)
+
+func (x OverflowPolicy) Enum() *OverflowPolicy {
+	p := new(OverflowPolicy)
+	*p = x
+	return p
+}
+
+func (x OverflowPolicy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OverflowPolicy) Descriptor() protoreflect.EnumDescriptor {
+	return file_pubsub_proto_enumTypes[1].Descriptor()
+}
+
+func (OverflowPolicy) Type() protoreflect.EnumType {
+	return &file_pubsub_proto_enumTypes[1]
+}
+
+func (x OverflowPolicy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OverflowPolicy.Descriptor instead.
+func (OverflowPolicy) EnumDescriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{1}
+}
+
+// 消息信封，服务端分发给订阅者的完整消息
+type Message struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`     // 主题
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"` // 消息内容
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`                                                                                     // 服务端分配的消息 ID
+	PublishTime   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=publish_time,json=publishTime,proto3" json:"publish_time,omitempty"`                                                // 发布时间
+	Topic         string                 `protobuf:"bytes,3,opt,name=topic,proto3" json:"topic,omitempty"`                                                                               // 主题
+	PublisherId   string                 `protobuf:"bytes,4,opt,name=publisher_id,json=publisherId,proto3" json:"publisher_id,omitempty"`                                                // 发布者 ID
+	Headers       map[string]string      `protobuf:"bytes,5,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 消息头
+	ContentType   string                 `protobuf:"bytes,6,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`                                                // 消息内容类型，如 text/plain、application/json
+	Payload       []byte                 `protobuf:"bytes,7,opt,name=payload,proto3" json:"payload,omitempty"`                                                                           // 消息内容
+	DeliverAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=deliver_at,json=deliverAt,proto3" json:"deliver_at,omitempty"`                                                      // 计划投递时间，立即投递的消息为空
+	OrderingKey   string                 `protobuf:"bytes,9,opt,name=ordering_key,json=orderingKey,proto3" json:"ordering_key,omitempty"`                                                // 排序键，相同排序键的消息属于同一分区，在消费者组中按发布顺序投递
+	Retain        bool                   `protobuf:"varint,10,opt,name=retain,proto3" json:"retain,omitempty"`                                                                           // 保留消息，服务端保存主题最新的保留消息并在订阅时立即投递
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PublishRequest) Reset() {
-	*x = PublishRequest{}
+func (x *Message) Reset() {
+	*x = Message{}
 	mi := &file_pubsub_proto_msgTypes[0]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PublishRequest) String() string {
+func (x *Message) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PublishRequest) ProtoMessage() {}
+func (*Message) ProtoMessage() {}
 
-func (x *PublishRequest) ProtoReflect() protoreflect.Message {
+func (x *Message) ProtoReflect() protoreflect.Message {
 	mi := &file_pubsub_proto_msgTypes[0]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -55,48 +176,116 @@ func (x *PublishRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PublishRequest.ProtoReflect.Descriptor instead.
-func (*PublishRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
 	return file_pubsub_proto_rawDescGZIP(), []int{0}
 }
 
-func (x *PublishRequest) GetTopic() string {
+func (x *Message) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Message) GetPublishTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PublishTime
+	}
+	return nil
+}
+
+func (x *Message) GetTopic() string {
 	if x != nil {
 		return x.Topic
 	}
 	return ""
 }
 
-func (x *PublishRequest) GetMessage() string {
+func (x *Message) GetPublisherId() string {
 	if x != nil {
-		return x.Message
+		return x.PublisherId
 	}
 	return ""
 }
 
-// 发布消息响应
-type PublishResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`                               // 是否成功
-	MessageCount  int32                  `protobuf:"varint,2,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"` // 成功发布的消息数量
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *Message) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
 }
 
-func (x *PublishResponse) Reset() {
-	*x = PublishResponse{}
+func (x *Message) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *Message) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *Message) GetDeliverAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DeliverAt
+	}
+	return nil
+}
+
+func (x *Message) GetOrderingKey() string {
+	if x != nil {
+		return x.OrderingKey
+	}
+	return ""
+}
+
+func (x *Message) GetRetain() bool {
+	if x != nil {
+		return x.Retain
+	}
+	return false
+}
+
+// 发布消息请求
+type PublishRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Topic            string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`                                                                               // 主题
+	Message          string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`                                                                           // 消息内容（旧版字段，payload 为空时使用）
+	PublisherId      string                 `protobuf:"bytes,3,opt,name=publisher_id,json=publisherId,proto3" json:"publisher_id,omitempty"`                                                // 发布者 ID，为空时使用客户端地址
+	Headers          map[string]string      `protobuf:"bytes,4,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 消息头
+	ContentType      string                 `protobuf:"bytes,5,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`                                                // 消息内容类型
+	Payload          []byte                 `protobuf:"bytes,6,opt,name=payload,proto3" json:"payload,omitempty"`                                                                           // 消息内容
+	Sequence         int64                  `protobuf:"varint,7,opt,name=sequence,proto3" json:"sequence,omitempty"`                                                                        // 客户端序列号，PublishStream 的确认中原样返回
+	DeliverAt        *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=deliver_at,json=deliverAt,proto3" json:"deliver_at,omitempty"`                                                      // 计划投递时间，到达该时间后才发布到主题
+	DelayMs          int64                  `protobuf:"varint,9,opt,name=delay_ms,json=delayMs,proto3" json:"delay_ms,omitempty"`                                                           // 延迟投递的毫秒数，与 deliver_at 只能指定一个
+	ProducerId       string                 `protobuf:"bytes,10,opt,name=producer_id,json=producerId,proto3" json:"producer_id,omitempty"`                                                  // 幂等发布的生产者 ID，与 producer_sequence 一起标识一条消息，重启后应保持不变
+	ProducerSequence int64                  `protobuf:"varint,11,opt,name=producer_sequence,json=producerSequence,proto3" json:"producer_sequence,omitempty"`                               // 生产者为每条消息分配的序列号，重试时保持不变
+	IdempotencyKey   string                 `protobuf:"bytes,12,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`                                      // 幂等键，与 producer_id/producer_sequence 二选一，同一主题下相同的键只发布一次
+	OrderingKey      string                 `protobuf:"bytes,13,opt,name=ordering_key,json=orderingKey,proto3" json:"ordering_key,omitempty"`                                               // 排序键，如订单号，相同排序键的消息在消费者组中按发布顺序投递给同一个消费者
+	Retain           bool                   `protobuf:"varint,14,opt,name=retain,proto3" json:"retain,omitempty"`                                                                           // 保留消息，替换主题当前的保留消息，新的订阅者订阅时立即收到它
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *PublishRequest) Reset() {
+	*x = PublishRequest{}
 	mi := &file_pubsub_proto_msgTypes[1]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PublishResponse) String() string {
+func (x *PublishRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PublishResponse) ProtoMessage() {}
+func (*PublishRequest) ProtoMessage() {}
 
-func (x *PublishResponse) ProtoReflect() protoreflect.Message {
+func (x *PublishRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_pubsub_proto_msgTypes[1]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -108,47 +297,136 @@ func (x *PublishResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PublishResponse.ProtoReflect.Descriptor instead.
-func (*PublishResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use PublishRequest.ProtoReflect.Descriptor instead.
+func (*PublishRequest) Descriptor() ([]byte, []int) {
 	return file_pubsub_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *PublishResponse) GetSuccess() bool {
+func (x *PublishRequest) GetTopic() string {
 	if x != nil {
-		return x.Success
+		return x.Topic
 	}
-	return false
+	return ""
 }
 
-func (x *PublishResponse) GetMessageCount() int32 {
+func (x *PublishRequest) GetMessage() string {
 	if x != nil {
-		return x.MessageCount
+		return x.Message
+	}
+	return ""
+}
+
+func (x *PublishRequest) GetPublisherId() string {
+	if x != nil {
+		return x.PublisherId
+	}
+	return ""
+}
+
+func (x *PublishRequest) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *PublishRequest) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *PublishRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *PublishRequest) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
 	}
 	return 0
 }
 
-// 订阅消息请求
-type SubscribeRequest struct {
+func (x *PublishRequest) GetDeliverAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DeliverAt
+	}
+	return nil
+}
+
+func (x *PublishRequest) GetDelayMs() int64 {
+	if x != nil {
+		return x.DelayMs
+	}
+	return 0
+}
+
+func (x *PublishRequest) GetProducerId() string {
+	if x != nil {
+		return x.ProducerId
+	}
+	return ""
+}
+
+func (x *PublishRequest) GetProducerSequence() int64 {
+	if x != nil {
+		return x.ProducerSequence
+	}
+	return 0
+}
+
+func (x *PublishRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *PublishRequest) GetOrderingKey() string {
+	if x != nil {
+		return x.OrderingKey
+	}
+	return ""
+}
+
+func (x *PublishRequest) GetRetain() bool {
+	if x != nil {
+		return x.Retain
+	}
+	return false
+}
+
+// 单条消息的发布确认
+type PublishAck struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"` // 主题
+	Sequence      int64                  `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`                   // 对应请求的客户端序列号
+	MessageId     string                 `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"` // 服务端分配的消息 ID，发布失败时为空
+	Code          int32                  `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`                           // gRPC 状态码，0 表示成功
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`                          // 发布失败的原因
+	Scheduled     bool                   `protobuf:"varint,5,opt,name=scheduled,proto3" json:"scheduled,omitempty"`                 // 消息已保存，将在计划投递时间发布
+	Duplicate     bool                   `protobuf:"varint,6,opt,name=duplicate,proto3" json:"duplicate,omitempty"`                 // 重复的消息，未再次发布，message_id 为首次发布的消息 ID
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SubscribeRequest) Reset() {
-	*x = SubscribeRequest{}
+func (x *PublishAck) Reset() {
+	*x = PublishAck{}
 	mi := &file_pubsub_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SubscribeRequest) String() string {
+func (x *PublishAck) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SubscribeRequest) ProtoMessage() {}
+func (*PublishAck) ProtoMessage() {}
 
-func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+func (x *PublishAck) ProtoReflect() protoreflect.Message {
 	mi := &file_pubsub_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -160,40 +438,76 @@ func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
-func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use PublishAck.ProtoReflect.Descriptor instead.
+func (*PublishAck) Descriptor() ([]byte, []int) {
 	return file_pubsub_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *SubscribeRequest) GetTopic() string {
+func (x *PublishAck) GetSequence() int64 {
 	if x != nil {
-		return x.Topic
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *PublishAck) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
 	}
 	return ""
 }
 
-// 订阅消息响应
-type SubscribeResponse struct {
+func (x *PublishAck) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *PublishAck) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *PublishAck) GetScheduled() bool {
+	if x != nil {
+		return x.Scheduled
+	}
+	return false
+}
+
+func (x *PublishAck) GetDuplicate() bool {
+	if x != nil {
+		return x.Duplicate
+	}
+	return false
+}
+
+// 发布消息响应
+type PublishResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"` // 收到的消息
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`                               // 是否成功
+	MessageCount  int32                  `protobuf:"varint,2,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"` // 成功发布的消息数量
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SubscribeResponse) Reset() {
-	*x = SubscribeResponse{}
+func (x *PublishResponse) Reset() {
+	*x = PublishResponse{}
 	mi := &file_pubsub_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SubscribeResponse) String() string {
+func (x *PublishResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SubscribeResponse) ProtoMessage() {}
+func (*PublishResponse) ProtoMessage() {}
 
-func (x *SubscribeResponse) ProtoReflect() protoreflect.Message {
+func (x *PublishResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_pubsub_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -205,49 +519,1829 @@ func (x *SubscribeResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SubscribeResponse.ProtoReflect.Descriptor instead.
-func (*SubscribeResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use PublishResponse.ProtoReflect.Descriptor instead.
+func (*PublishResponse) Descriptor() ([]byte, []int) {
 	return file_pubsub_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *SubscribeResponse) GetMessage() string {
+func (x *PublishResponse) GetSuccess() bool {
 	if x != nil {
-		return x.Message
+		return x.Success
 	}
-	return ""
+	return false
+}
+
+func (x *PublishResponse) GetMessageCount() int32 {
+	if x != nil {
+		return x.MessageCount
+	}
+	return 0
+}
+
+// 订阅消息请求
+type SubscribeRequest struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Topic               string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`                                                                      // 主题
+	Group               string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`                                                                      // 消费者组（仅持久化模式），为空时接收全部消息
+	Consumer            string                 `protobuf:"bytes,3,opt,name=consumer,proto3" json:"consumer,omitempty"`                                                                // 消费者名称，为空时由服务端生成
+	VisibilityTimeoutMs int64                  `protobuf:"varint,4,opt,name=visibility_timeout_ms,json=visibilityTimeoutMs,proto3" json:"visibility_timeout_ms,omitempty"`            // 未确认消息重新投递的超时时间（毫秒），0 表示使用服务端默认值
+	Topics              []string               `protobuf:"bytes,5,rep,name=topics,proto3" json:"topics,omitempty"`                                                                    // 订阅的多个主题，支持 glob 模式（如 orders.*），与 topic 合并
+	SubscriptionId      string                 `protobuf:"bytes,6,opt,name=subscription_id,json=subscriptionId,proto3" json:"subscription_id,omitempty"`                              // 订阅 ID，用于 UpdateSubscription，为空时由服务端生成
+	StartPosition       StartPosition          `protobuf:"varint,7,opt,name=start_position,json=startPosition,proto3,enum=pubsub.StartPosition" json:"start_position,omitempty"`      // 起始位置，默认只接收新消息
+	StartId             string                 `protobuf:"bytes,8,opt,name=start_id,json=startId,proto3" json:"start_id,omitempty"`                                                   // START_AFTER_ID 时的消息 ID
+	StartTime           *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`                                             // START_FROM_TIME 时的起始时间
+	LastN               int32                  `protobuf:"varint,10,opt,name=last_n,json=lastN,proto3" json:"last_n,omitempty"`                                                       // START_LAST_N 时回放的消息数量
+	BufferSize          int32                  `protobuf:"varint,11,opt,name=buffer_size,json=bufferSize,proto3" json:"buffer_size,omitempty"`                                        // 服务端为该订阅缓冲的最大消息数，0 表示使用服务端默认值
+	OverflowPolicy      OverflowPolicy         `protobuf:"varint,12,opt,name=overflow_policy,json=overflowPolicy,proto3,enum=pubsub.OverflowPolicy" json:"overflow_policy,omitempty"` // 缓冲区满时的处理策略
+	HeartbeatIntervalMs int64                  `protobuf:"varint,13,opt,name=heartbeat_interval_ms,json=heartbeatIntervalMs,proto3" json:"heartbeat_interval_ms,omitempty"`           // 空闲时服务端发送心跳的间隔（毫秒），0 表示使用服务端默认值
+	MaxDeliveryAttempts int32                  `protobuf:"varint,14,opt,name=max_delivery_attempts,json=maxDeliveryAttempts,proto3" json:"max_delivery_attempts,omitempty"`           // 消费者组中每条消息的最大投递次数，超过后移入死信主题，0 表示使用服务端默认值
+	DeadLetterTopic     string                 `protobuf:"bytes,15,opt,name=dead_letter_topic,json=deadLetterTopic,proto3" json:"dead_letter_topic,omitempty"`                        // 死信主题，为空时使用服务端默认的 <主题><后缀>
+	Filter              string                 `protobuf:"bytes,16,opt,name=filter,proto3" json:"filter,omitempty"`                                                                   // 消息头过滤表达式，如 region = "eu" AND priority >= 5，为空时接收全部消息
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_pubsub_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SubscribeRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetConsumer() string {
+	if x != nil {
+		return x.Consumer
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetVisibilityTimeoutMs() int64 {
+	if x != nil {
+		return x.VisibilityTimeoutMs
+	}
+	return 0
+}
+
+func (x *SubscribeRequest) GetTopics() []string {
+	if x != nil {
+		return x.Topics
+	}
+	return nil
+}
+
+func (x *SubscribeRequest) GetSubscriptionId() string {
+	if x != nil {
+		return x.SubscriptionId
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetStartPosition() StartPosition {
+	if x != nil {
+		return x.StartPosition
+	}
+	return StartPosition_START_LATEST
+}
+
+func (x *SubscribeRequest) GetStartId() string {
+	if x != nil {
+		return x.StartId
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetStartTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *SubscribeRequest) GetLastN() int32 {
+	if x != nil {
+		return x.LastN
+	}
+	return 0
+}
+
+func (x *SubscribeRequest) GetBufferSize() int32 {
+	if x != nil {
+		return x.BufferSize
+	}
+	return 0
+}
+
+func (x *SubscribeRequest) GetOverflowPolicy() OverflowPolicy {
+	if x != nil {
+		return x.OverflowPolicy
+	}
+	return OverflowPolicy_OVERFLOW_DEFAULT
+}
+
+func (x *SubscribeRequest) GetHeartbeatIntervalMs() int64 {
+	if x != nil {
+		return x.HeartbeatIntervalMs
+	}
+	return 0
+}
+
+func (x *SubscribeRequest) GetMaxDeliveryAttempts() int32 {
+	if x != nil {
+		return x.MaxDeliveryAttempts
+	}
+	return 0
+}
+
+func (x *SubscribeRequest) GetDeadLetterTopic() string {
+	if x != nil {
+		return x.DeadLetterTopic
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+// 订阅消息响应
+type SubscribeResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Message         string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`                                         // 收到的消息内容（旧版字段，与 envelope.payload 相同）
+	Id              string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`                                                   // 消息 ID（持久化模式下为 Redis Stream ID）
+	Envelope        *Message               `protobuf:"bytes,3,opt,name=envelope,proto3" json:"envelope,omitempty"`                                       // 完整的消息信封
+	Topic           string                 `protobuf:"bytes,4,opt,name=topic,proto3" json:"topic,omitempty"`                                             // 消息所属的主题
+	Pattern         string                 `protobuf:"bytes,5,opt,name=pattern,proto3" json:"pattern,omitempty"`                                         // 匹配该消息的订阅模式，精确订阅时为空
+	SubscriptionId  string                 `protobuf:"bytes,6,opt,name=subscription_id,json=subscriptionId,proto3" json:"subscription_id,omitempty"`     // 订阅 ID
+	DroppedCount    uint64                 `protobuf:"varint,7,opt,name=dropped_count,json=droppedCount,proto3" json:"dropped_count,omitempty"`          // 该订阅因缓冲区满累计丢弃的消息数
+	Heartbeat       bool                   `protobuf:"varint,8,opt,name=heartbeat,proto3" json:"heartbeat,omitempty"`                                    // 心跳消息，不携带消息内容，用于检测空闲连接是否存活
+	DeliveryAttempt int64                  `protobuf:"varint,9,opt,name=delivery_attempt,json=deliveryAttempt,proto3" json:"delivery_attempt,omitempty"` // 消费者组中该消息的第几次投递
+	Partition       int32                  `protobuf:"varint,10,opt,name=partition,proto3" json:"partition,omitempty"`                                   // 服务端启用分区时消息所属的分区（从 0 开始）
+	Retained        bool                   `protobuf:"varint,11,opt,name=retained,proto3" json:"retained,omitempty"`                                     // 订阅时投递的保留消息，而不是订阅后发布的新消息
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *SubscribeResponse) Reset() {
+	*x = SubscribeResponse{}
+	mi := &file_pubsub_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeResponse) ProtoMessage() {}
+
+func (x *SubscribeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeResponse.ProtoReflect.Descriptor instead.
+func (*SubscribeResponse) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SubscribeResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SubscribeResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SubscribeResponse) GetEnvelope() *Message {
+	if x != nil {
+		return x.Envelope
+	}
+	return nil
+}
+
+func (x *SubscribeResponse) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *SubscribeResponse) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *SubscribeResponse) GetSubscriptionId() string {
+	if x != nil {
+		return x.SubscriptionId
+	}
+	return ""
+}
+
+func (x *SubscribeResponse) GetDroppedCount() uint64 {
+	if x != nil {
+		return x.DroppedCount
+	}
+	return 0
+}
+
+func (x *SubscribeResponse) GetHeartbeat() bool {
+	if x != nil {
+		return x.Heartbeat
+	}
+	return false
+}
+
+func (x *SubscribeResponse) GetDeliveryAttempt() int64 {
+	if x != nil {
+		return x.DeliveryAttempt
+	}
+	return 0
+}
+
+func (x *SubscribeResponse) GetPartition() int32 {
+	if x != nil {
+		return x.Partition
+	}
+	return 0
+}
+
+func (x *SubscribeResponse) GetRetained() bool {
+	if x != nil {
+		return x.Retained
+	}
+	return false
+}
+
+// 更新订阅请求
+type UpdateSubscriptionRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	SubscriptionId string                 `protobuf:"bytes,1,opt,name=subscription_id,json=subscriptionId,proto3" json:"subscription_id,omitempty"` // 订阅 ID
+	AddTopics      []string               `protobuf:"bytes,2,rep,name=add_topics,json=addTopics,proto3" json:"add_topics,omitempty"`                // 要添加的主题或模式
+	RemoveTopics   []string               `protobuf:"bytes,3,rep,name=remove_topics,json=removeTopics,proto3" json:"remove_topics,omitempty"`       // 要移除的主题或模式
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *UpdateSubscriptionRequest) Reset() {
+	*x = UpdateSubscriptionRequest{}
+	mi := &file_pubsub_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateSubscriptionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateSubscriptionRequest) ProtoMessage() {}
+
+func (x *UpdateSubscriptionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateSubscriptionRequest.ProtoReflect.Descriptor instead.
+func (*UpdateSubscriptionRequest) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateSubscriptionRequest) GetSubscriptionId() string {
+	if x != nil {
+		return x.SubscriptionId
+	}
+	return ""
+}
+
+func (x *UpdateSubscriptionRequest) GetAddTopics() []string {
+	if x != nil {
+		return x.AddTopics
+	}
+	return nil
+}
+
+func (x *UpdateSubscriptionRequest) GetRemoveTopics() []string {
+	if x != nil {
+		return x.RemoveTopics
+	}
+	return nil
+}
+
+// 更新订阅响应
+type UpdateSubscriptionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topics        []string               `protobuf:"bytes,1,rep,name=topics,proto3" json:"topics,omitempty"` // 更新后订阅的全部主题和模式
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateSubscriptionResponse) Reset() {
+	*x = UpdateSubscriptionResponse{}
+	mi := &file_pubsub_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateSubscriptionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateSubscriptionResponse) ProtoMessage() {}
+
+func (x *UpdateSubscriptionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateSubscriptionResponse.ProtoReflect.Descriptor instead.
+func (*UpdateSubscriptionResponse) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdateSubscriptionResponse) GetTopics() []string {
+	if x != nil {
+		return x.Topics
+	}
+	return nil
+}
+
+// 确认消息请求
+type AckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"` // 主题
+	Group         string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"` // 消费者组
+	Ids           []string               `protobuf:"bytes,3,rep,name=ids,proto3" json:"ids,omitempty"`     // 要确认的消息 ID
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AckRequest) Reset() {
+	*x = AckRequest{}
+	mi := &file_pubsub_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AckRequest) ProtoMessage() {}
+
+func (x *AckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AckRequest.ProtoReflect.Descriptor instead.
+func (*AckRequest) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AckRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *AckRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *AckRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+// 确认消息响应
+type AckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AckedCount    int32                  `protobuf:"varint,1,opt,name=acked_count,json=ackedCount,proto3" json:"acked_count,omitempty"` // 成功确认的消息数量
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AckResponse) Reset() {
+	*x = AckResponse{}
+	mi := &file_pubsub_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AckResponse) ProtoMessage() {}
+
+func (x *AckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AckResponse.ProtoReflect.Descriptor instead.
+func (*AckResponse) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AckResponse) GetAckedCount() int32 {
+	if x != nil {
+		return x.AckedCount
+	}
+	return 0
+}
+
+// 否认消息请求
+type NackRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"` // 主题
+	Group         string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"` // 消费者组
+	Ids           []string               `protobuf:"bytes,3,rep,name=ids,proto3" json:"ids,omitempty"`     // 处理失败的消息 ID
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"` // 失败原因，移入死信主题时记录在消息头中
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NackRequest) Reset() {
+	*x = NackRequest{}
+	mi := &file_pubsub_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NackRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NackRequest) ProtoMessage() {}
+
+func (x *NackRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NackRequest.ProtoReflect.Descriptor instead.
+func (*NackRequest) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *NackRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *NackRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *NackRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *NackRequest) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// 否认消息响应
+type NackResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NackedCount   int32                  `protobuf:"varint,1,opt,name=nacked_count,json=nackedCount,proto3" json:"nacked_count,omitempty"` // 将重新投递的消息数量
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NackResponse) Reset() {
+	*x = NackResponse{}
+	mi := &file_pubsub_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NackResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NackResponse) ProtoMessage() {}
+
+func (x *NackResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NackResponse.ProtoReflect.Descriptor instead.
+func (*NackResponse) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *NackResponse) GetNackedCount() int32 {
+	if x != nil {
+		return x.NackedCount
+	}
+	return 0
+}
+
+// 列出主题请求
+type ListTopicsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pattern       string                 `protobuf:"bytes,1,opt,name=pattern,proto3" json:"pattern,omitempty"` // glob 模式，为空时列出全部主题
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTopicsRequest) Reset() {
+	*x = ListTopicsRequest{}
+	mi := &file_pubsub_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTopicsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTopicsRequest) ProtoMessage() {}
+
+func (x *ListTopicsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTopicsRequest.ProtoReflect.Descriptor instead.
+func (*ListTopicsRequest) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListTopicsRequest) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+// 列出主题响应
+type ListTopicsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topics        []string               `protobuf:"bytes,1,rep,name=topics,proto3" json:"topics,omitempty"` // 按名称排序的主题
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTopicsResponse) Reset() {
+	*x = ListTopicsResponse{}
+	mi := &file_pubsub_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTopicsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTopicsResponse) ProtoMessage() {}
+
+func (x *ListTopicsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTopicsResponse.ProtoReflect.Descriptor instead.
+func (*ListTopicsResponse) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListTopicsResponse) GetTopics() []string {
+	if x != nil {
+		return x.Topics
+	}
+	return nil
+}
+
+// 主题统计请求
+type GetTopicStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"` // 主题
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTopicStatsRequest) Reset() {
+	*x = GetTopicStatsRequest{}
+	mi := &file_pubsub_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTopicStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTopicStatsRequest) ProtoMessage() {}
+
+func (x *GetTopicStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTopicStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetTopicStatsRequest) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetTopicStatsRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+// 主题统计
+type TopicStats struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Topic            string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`                                                // 主题
+	LocalSubscribers int64                  `protobuf:"varint,2,opt,name=local_subscribers,json=localSubscribers,proto3" json:"local_subscribers,omitempty"` // 本服务器上的订阅者数量，包括匹配该主题的模式订阅
+	RedisSubscribers int64                  `protobuf:"varint,3,opt,name=redis_subscribers,json=redisSubscribers,proto3" json:"redis_subscribers,omitempty"` // 消息代理中的订阅数（Redis PUBSUB NUMSUB），服务端分发时每台服务器只计一次
+	PublishedCount   uint64                 `protobuf:"varint,4,opt,name=published_count,json=publishedCount,proto3" json:"published_count,omitempty"`       // 服务器启动以来通过本服务器发布的消息数
+	PublishRate      float64                `protobuf:"fixed64,5,opt,name=publish_rate,json=publishRate,proto3" json:"publish_rate,omitempty"`               // 最近一分钟的平均发布速率（条/秒）
+	LastMessageTime  *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=last_message_time,json=lastMessageTime,proto3" json:"last_message_time,omitempty"`   // 最后一条消息的发布时间
+	StreamLength     int64                  `protobuf:"varint,7,opt,name=stream_length,json=streamLength,proto3" json:"stream_length,omitempty"`             // 持久化模式下主题 Stream 中的消息数
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *TopicStats) Reset() {
+	*x = TopicStats{}
+	mi := &file_pubsub_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopicStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopicStats) ProtoMessage() {}
+
+func (x *TopicStats) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopicStats.ProtoReflect.Descriptor instead.
+func (*TopicStats) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *TopicStats) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *TopicStats) GetLocalSubscribers() int64 {
+	if x != nil {
+		return x.LocalSubscribers
+	}
+	return 0
+}
+
+func (x *TopicStats) GetRedisSubscribers() int64 {
+	if x != nil {
+		return x.RedisSubscribers
+	}
+	return 0
+}
+
+func (x *TopicStats) GetPublishedCount() uint64 {
+	if x != nil {
+		return x.PublishedCount
+	}
+	return 0
+}
+
+func (x *TopicStats) GetPublishRate() float64 {
+	if x != nil {
+		return x.PublishRate
+	}
+	return 0
+}
+
+func (x *TopicStats) GetLastMessageTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastMessageTime
+	}
+	return nil
+}
+
+func (x *TopicStats) GetStreamLength() int64 {
+	if x != nil {
+		return x.StreamLength
+	}
+	return 0
+}
+
+// 删除主题请求
+type DeleteTopicRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"` // 主题
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteTopicRequest) Reset() {
+	*x = DeleteTopicRequest{}
+	mi := &file_pubsub_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteTopicRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteTopicRequest) ProtoMessage() {}
+
+func (x *DeleteTopicRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteTopicRequest.ProtoReflect.Descriptor instead.
+func (*DeleteTopicRequest) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *DeleteTopicRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+// 删除主题响应
+type DeleteTopicResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       bool                   `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"` // 主题是否已删除
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteTopicResponse) Reset() {
+	*x = DeleteTopicResponse{}
+	mi := &file_pubsub_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteTopicResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteTopicResponse) ProtoMessage() {}
+
+func (x *DeleteTopicResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteTopicResponse.ProtoReflect.Descriptor instead.
+func (*DeleteTopicResponse) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *DeleteTopicResponse) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+// 重新投递死信请求
+type RedriveRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	DeadLetterTopic string                 `protobuf:"bytes,1,opt,name=dead_letter_topic,json=deadLetterTopic,proto3" json:"dead_letter_topic,omitempty"` // 死信主题
+	Ids             []string               `protobuf:"bytes,2,rep,name=ids,proto3" json:"ids,omitempty"`                                                  // 要重新投递的消息 ID，为空时按顺序重新投递
+	MaxMessages     int32                  `protobuf:"varint,3,opt,name=max_messages,json=maxMessages,proto3" json:"max_messages,omitempty"`              // ids 为空时最多重新投递的消息数，0 表示全部
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RedriveRequest) Reset() {
+	*x = RedriveRequest{}
+	mi := &file_pubsub_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RedriveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RedriveRequest) ProtoMessage() {}
+
+func (x *RedriveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RedriveRequest.ProtoReflect.Descriptor instead.
+func (*RedriveRequest) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *RedriveRequest) GetDeadLetterTopic() string {
+	if x != nil {
+		return x.DeadLetterTopic
+	}
+	return ""
+}
+
+func (x *RedriveRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *RedriveRequest) GetMaxMessages() int32 {
+	if x != nil {
+		return x.MaxMessages
+	}
+	return 0
+}
+
+// 重新投递死信响应
+type RedriveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RedrivenCount int32                  `protobuf:"varint,1,opt,name=redriven_count,json=redrivenCount,proto3" json:"redriven_count,omitempty"` // 已发布回原主题的消息数量
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RedriveResponse) Reset() {
+	*x = RedriveResponse{}
+	mi := &file_pubsub_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RedriveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RedriveResponse) ProtoMessage() {}
+
+func (x *RedriveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RedriveResponse.ProtoReflect.Descriptor instead.
+func (*RedriveResponse) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *RedriveResponse) GetRedrivenCount() int32 {
+	if x != nil {
+		return x.RedrivenCount
+	}
+	return 0
+}
+
+// 清除保留消息请求
+type ClearRetainedRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"` // 主题
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClearRetainedRequest) Reset() {
+	*x = ClearRetainedRequest{}
+	mi := &file_pubsub_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClearRetainedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearRetainedRequest) ProtoMessage() {}
+
+func (x *ClearRetainedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearRetainedRequest.ProtoReflect.Descriptor instead.
+func (*ClearRetainedRequest) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ClearRetainedRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+// 清除保留消息响应
+type ClearRetainedResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cleared       bool                   `protobuf:"varint,1,opt,name=cleared,proto3" json:"cleared,omitempty"` // 主题是否有保留消息
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClearRetainedResponse) Reset() {
+	*x = ClearRetainedResponse{}
+	mi := &file_pubsub_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClearRetainedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearRetainedResponse) ProtoMessage() {}
+
+func (x *ClearRetainedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearRetainedResponse.ProtoReflect.Descriptor instead.
+func (*ClearRetainedResponse) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ClearRetainedResponse) GetCleared() bool {
+	if x != nil {
+		return x.Cleared
+	}
+	return false
+}
+
+// 请求消息，服务端在消息头 x-reply-to 和 x-correlation-id 中附加回复主题和关联 ID
+type RequestRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`                                                                               // 请求主题
+	Headers       map[string]string      `protobuf:"bytes,2,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 消息头
+	ContentType   string                 `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`                                                // 消息内容类型
+	Payload       []byte                 `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`                                                                           // 消息内容
+	PublisherId   string                 `protobuf:"bytes,5,opt,name=publisher_id,json=publisherId,proto3" json:"publisher_id,omitempty"`                                                // 发布者 ID，为空时使用客户端地址
+	TimeoutMs     int64                  `protobuf:"varint,6,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`                                                     // 等待响应的超时时间（毫秒），0 表示使用服务端默认值
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestRequest) Reset() {
+	*x = RequestRequest{}
+	mi := &file_pubsub_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestRequest) ProtoMessage() {}
+
+func (x *RequestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestRequest.ProtoReflect.Descriptor instead.
+func (*RequestRequest) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *RequestRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *RequestRequest) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *RequestRequest) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *RequestRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *RequestRequest) GetPublisherId() string {
+	if x != nil {
+		return x.PublisherId
+	}
+	return ""
+}
+
+func (x *RequestRequest) GetTimeoutMs() int64 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+// 请求的响应
+type RequestResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reply         *Message               `protobuf:"bytes,1,opt,name=reply,proto3" json:"reply,omitempty"`                                      // 第一条响应消息
+	RequestId     string                 `protobuf:"bytes,2,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`             // 请求消息的 ID
+	CorrelationId string                 `protobuf:"bytes,3,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"` // 请求的关联 ID
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestResponse) Reset() {
+	*x = RequestResponse{}
+	mi := &file_pubsub_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestResponse) ProtoMessage() {}
+
+func (x *RequestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestResponse.ProtoReflect.Descriptor instead.
+func (*RequestResponse) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *RequestResponse) GetReply() *Message {
+	if x != nil {
+		return x.Reply
+	}
+	return nil
+}
+
+func (x *RequestResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *RequestResponse) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+// 推送订阅，服务端将消息签名后 POST 到 endpoint，失败时按退避间隔重试
+type PushSubscription struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Name                string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`                                                             // 订阅名称，为空时由服务端生成
+	Topic               string                 `protobuf:"bytes,2,opt,name=topic,proto3" json:"topic,omitempty"`                                                           // 主题
+	Endpoint            string                 `protobuf:"bytes,3,opt,name=endpoint,proto3" json:"endpoint,omitempty"`                                                     // 接收消息的 HTTP(S) 地址
+	Secret              string                 `protobuf:"bytes,4,opt,name=secret,proto3" json:"secret,omitempty"`                                                         // 签名密钥，为空时由服务端生成；只在创建时返回
+	Filter              string                 `protobuf:"bytes,5,opt,name=filter,proto3" json:"filter,omitempty"`                                                         // 消息头过滤表达式，为空时推送全部消息
+	MaxDeliveryAttempts int32                  `protobuf:"varint,6,opt,name=max_delivery_attempts,json=maxDeliveryAttempts,proto3" json:"max_delivery_attempts,omitempty"` // 每条消息的最大推送次数，超过后移入死信主题，0 表示使用服务端默认值
+	MinBackoffMs        int64                  `protobuf:"varint,7,opt,name=min_backoff_ms,json=minBackoffMs,proto3" json:"min_backoff_ms,omitempty"`                      // 第一次重试前的等待时间（毫秒），之后每次加倍，0 表示 1 秒
+	MaxBackoffMs        int64                  `protobuf:"varint,8,opt,name=max_backoff_ms,json=maxBackoffMs,proto3" json:"max_backoff_ms,omitempty"`                      // 重试等待时间的上限（毫秒），0 表示 1 分钟
+	DeadLetterTopic     string                 `protobuf:"bytes,9,opt,name=dead_letter_topic,json=deadLetterTopic,proto3" json:"dead_letter_topic,omitempty"`              // 死信主题，为空时使用服务端默认的 <主题><后缀>
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *PushSubscription) Reset() {
+	*x = PushSubscription{}
+	mi := &file_pubsub_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushSubscription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushSubscription) ProtoMessage() {}
+
+func (x *PushSubscription) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushSubscription.ProtoReflect.Descriptor instead.
+func (*PushSubscription) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *PushSubscription) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PushSubscription) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *PushSubscription) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *PushSubscription) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+func (x *PushSubscription) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+func (x *PushSubscription) GetMaxDeliveryAttempts() int32 {
+	if x != nil {
+		return x.MaxDeliveryAttempts
+	}
+	return 0
+}
+
+func (x *PushSubscription) GetMinBackoffMs() int64 {
+	if x != nil {
+		return x.MinBackoffMs
+	}
+	return 0
+}
+
+func (x *PushSubscription) GetMaxBackoffMs() int64 {
+	if x != nil {
+		return x.MaxBackoffMs
+	}
+	return 0
+}
+
+func (x *PushSubscription) GetDeadLetterTopic() string {
+	if x != nil {
+		return x.DeadLetterTopic
+	}
+	return ""
+}
+
+// 创建推送订阅请求
+type CreatePushSubscriptionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subscription  *PushSubscription      `protobuf:"bytes,1,opt,name=subscription,proto3" json:"subscription,omitempty"` // 推送订阅
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreatePushSubscriptionRequest) Reset() {
+	*x = CreatePushSubscriptionRequest{}
+	mi := &file_pubsub_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatePushSubscriptionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePushSubscriptionRequest) ProtoMessage() {}
+
+func (x *CreatePushSubscriptionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePushSubscriptionRequest.ProtoReflect.Descriptor instead.
+func (*CreatePushSubscriptionRequest) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *CreatePushSubscriptionRequest) GetSubscription() *PushSubscription {
+	if x != nil {
+		return x.Subscription
+	}
+	return nil
+}
+
+// 删除推送订阅请求
+type DeletePushSubscriptionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // 订阅名称
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePushSubscriptionRequest) Reset() {
+	*x = DeletePushSubscriptionRequest{}
+	mi := &file_pubsub_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePushSubscriptionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePushSubscriptionRequest) ProtoMessage() {}
+
+func (x *DeletePushSubscriptionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePushSubscriptionRequest.ProtoReflect.Descriptor instead.
+func (*DeletePushSubscriptionRequest) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *DeletePushSubscriptionRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// 删除推送订阅响应
+type DeletePushSubscriptionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       bool                   `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"` // 订阅是否存在
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePushSubscriptionResponse) Reset() {
+	*x = DeletePushSubscriptionResponse{}
+	mi := &file_pubsub_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePushSubscriptionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePushSubscriptionResponse) ProtoMessage() {}
+
+func (x *DeletePushSubscriptionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePushSubscriptionResponse.ProtoReflect.Descriptor instead.
+func (*DeletePushSubscriptionResponse) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *DeletePushSubscriptionResponse) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+// 列出推送订阅请求
+type ListPushSubscriptionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPushSubscriptionsRequest) Reset() {
+	*x = ListPushSubscriptionsRequest{}
+	mi := &file_pubsub_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPushSubscriptionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPushSubscriptionsRequest) ProtoMessage() {}
+
+func (x *ListPushSubscriptionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPushSubscriptionsRequest.ProtoReflect.Descriptor instead.
+func (*ListPushSubscriptionsRequest) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{28}
+}
+
+// 列出推送订阅响应
+type ListPushSubscriptionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subscriptions []*PushSubscription    `protobuf:"bytes,1,rep,name=subscriptions,proto3" json:"subscriptions,omitempty"` // 推送订阅，不包含签名密钥
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPushSubscriptionsResponse) Reset() {
+	*x = ListPushSubscriptionsResponse{}
+	mi := &file_pubsub_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPushSubscriptionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPushSubscriptionsResponse) ProtoMessage() {}
+
+func (x *ListPushSubscriptionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pubsub_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPushSubscriptionsResponse.ProtoReflect.Descriptor instead.
+func (*ListPushSubscriptionsResponse) Descriptor() ([]byte, []int) {
+	return file_pubsub_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ListPushSubscriptionsResponse) GetSubscriptions() []*PushSubscription {
+	if x != nil {
+		return x.Subscriptions
+	}
+	return nil
 }
 
 var File_pubsub_proto protoreflect.FileDescriptor
 
-var file_pubsub_proto_rawDesc = string([]byte{
-	0x0a, 0x0c, 0x70, 0x75, 0x62, 0x73, 0x75, 0x62, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06,
-	0x70, 0x75, 0x62, 0x73, 0x75, 0x62, 0x22, 0x40, 0x0a, 0x0e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73,
-	0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x70, 0x69,
-	0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x70, 0x69, 0x63, 0x12, 0x18,
-	0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x50, 0x0a, 0x0f, 0x50, 0x75, 0x62, 0x6c,
-	0x69, 0x73, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x73,
-	0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x75,
-	0x63, 0x63, 0x65, 0x73, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
-	0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x6d, 0x65,
-	0x73, 0x73, 0x61, 0x67, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x28, 0x0a, 0x10, 0x53, 0x75,
-	0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14,
-	0x0a, 0x05, 0x74, 0x6f, 0x70, 0x69, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74,
-	0x6f, 0x70, 0x69, 0x63, 0x22, 0x2d, 0x0a, 0x11, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62,
-	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73,
-	0x73, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73,
-	0x61, 0x67, 0x65, 0x32, 0x8a, 0x01, 0x0a, 0x06, 0x50, 0x75, 0x62, 0x53, 0x75, 0x62, 0x12, 0x3c,
-	0x0a, 0x07, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x12, 0x16, 0x2e, 0x70, 0x75, 0x62, 0x73,
-	0x75, 0x62, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x1a, 0x17, 0x2e, 0x70, 0x75, 0x62, 0x73, 0x75, 0x62, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69,
-	0x73, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01, 0x12, 0x42, 0x0a, 0x09,
-	0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x12, 0x18, 0x2e, 0x70, 0x75, 0x62, 0x73,
-	0x75, 0x62, 0x2e, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x70, 0x75, 0x62, 0x73, 0x75, 0x62, 0x2e, 0x53, 0x75, 0x62,
-	0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01,
-	0x42, 0x10, 0x5a, 0x0e, 0x2e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x70, 0x75, 0x62, 0x73,
-	0x75, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
-})
+const file_pubsub_proto_rawDesc = "" +
+	"\n" +
+	"\fpubsub.proto\x12\x06pubsub\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb8\x03\n" +
+	"\aMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12=\n" +
+	"\fpublish_time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\vpublishTime\x12\x14\n" +
+	"\x05topic\x18\x03 \x01(\tR\x05topic\x12!\n" +
+	"\fpublisher_id\x18\x04 \x01(\tR\vpublisherId\x126\n" +
+	"\aheaders\x18\x05 \x03(\v2\x1c.pubsub.Message.HeadersEntryR\aheaders\x12!\n" +
+	"\fcontent_type\x18\x06 \x01(\tR\vcontentType\x12\x18\n" +
+	"\apayload\x18\a \x01(\fR\apayload\x129\n" +
+	"\n" +
+	"deliver_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tdeliverAt\x12!\n" +
+	"\fordering_key\x18\t \x01(\tR\vorderingKey\x12\x16\n" +
+	"\x06retain\x18\n" +
+	" \x01(\bR\x06retain\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xbf\x04\n" +
+	"\x0ePublishRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12!\n" +
+	"\fpublisher_id\x18\x03 \x01(\tR\vpublisherId\x12=\n" +
+	"\aheaders\x18\x04 \x03(\v2#.pubsub.PublishRequest.HeadersEntryR\aheaders\x12!\n" +
+	"\fcontent_type\x18\x05 \x01(\tR\vcontentType\x12\x18\n" +
+	"\apayload\x18\x06 \x01(\fR\apayload\x12\x1a\n" +
+	"\bsequence\x18\a \x01(\x03R\bsequence\x129\n" +
+	"\n" +
+	"deliver_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tdeliverAt\x12\x19\n" +
+	"\bdelay_ms\x18\t \x01(\x03R\adelayMs\x12\x1f\n" +
+	"\vproducer_id\x18\n" +
+	" \x01(\tR\n" +
+	"producerId\x12+\n" +
+	"\x11producer_sequence\x18\v \x01(\x03R\x10producerSequence\x12'\n" +
+	"\x0fidempotency_key\x18\f \x01(\tR\x0eidempotencyKey\x12!\n" +
+	"\fordering_key\x18\r \x01(\tR\vorderingKey\x12\x16\n" +
+	"\x06retain\x18\x0e \x01(\bR\x06retain\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xad\x01\n" +
+	"\n" +
+	"PublishAck\x12\x1a\n" +
+	"\bsequence\x18\x01 \x01(\x03R\bsequence\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x02 \x01(\tR\tmessageId\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\x05R\x04code\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\x1c\n" +
+	"\tscheduled\x18\x05 \x01(\bR\tscheduled\x12\x1c\n" +
+	"\tduplicate\x18\x06 \x01(\bR\tduplicate\"P\n" +
+	"\x0fPublishResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12#\n" +
+	"\rmessage_count\x18\x02 \x01(\x05R\fmessageCount\"\x88\x05\n" +
+	"\x10SubscribeRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x14\n" +
+	"\x05group\x18\x02 \x01(\tR\x05group\x12\x1a\n" +
+	"\bconsumer\x18\x03 \x01(\tR\bconsumer\x122\n" +
+	"\x15visibility_timeout_ms\x18\x04 \x01(\x03R\x13visibilityTimeoutMs\x12\x16\n" +
+	"\x06topics\x18\x05 \x03(\tR\x06topics\x12'\n" +
+	"\x0fsubscription_id\x18\x06 \x01(\tR\x0esubscriptionId\x12<\n" +
+	"\x0estart_position\x18\a \x01(\x0e2\x15.pubsub.StartPositionR\rstartPosition\x12\x19\n" +
+	"\bstart_id\x18\b \x01(\tR\astartId\x129\n" +
+	"\n" +
+	"start_time\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x12\x15\n" +
+	"\x06last_n\x18\n" +
+	" \x01(\x05R\x05lastN\x12\x1f\n" +
+	"\vbuffer_size\x18\v \x01(\x05R\n" +
+	"bufferSize\x12?\n" +
+	"\x0foverflow_policy\x18\f \x01(\x0e2\x16.pubsub.OverflowPolicyR\x0eoverflowPolicy\x122\n" +
+	"\x15heartbeat_interval_ms\x18\r \x01(\x03R\x13heartbeatIntervalMs\x122\n" +
+	"\x15max_delivery_attempts\x18\x0e \x01(\x05R\x13maxDeliveryAttempts\x12*\n" +
+	"\x11dead_letter_topic\x18\x0f \x01(\tR\x0fdeadLetterTopic\x12\x16\n" +
+	"\x06filter\x18\x10 \x01(\tR\x06filter\"\xeb\x02\n" +
+	"\x11SubscribeResponse\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12+\n" +
+	"\benvelope\x18\x03 \x01(\v2\x0f.pubsub.MessageR\benvelope\x12\x14\n" +
+	"\x05topic\x18\x04 \x01(\tR\x05topic\x12\x18\n" +
+	"\apattern\x18\x05 \x01(\tR\apattern\x12'\n" +
+	"\x0fsubscription_id\x18\x06 \x01(\tR\x0esubscriptionId\x12#\n" +
+	"\rdropped_count\x18\a \x01(\x04R\fdroppedCount\x12\x1c\n" +
+	"\theartbeat\x18\b \x01(\bR\theartbeat\x12)\n" +
+	"\x10delivery_attempt\x18\t \x01(\x03R\x0fdeliveryAttempt\x12\x1c\n" +
+	"\tpartition\x18\n" +
+	" \x01(\x05R\tpartition\x12\x1a\n" +
+	"\bretained\x18\v \x01(\bR\bretained\"\x88\x01\n" +
+	"\x19UpdateSubscriptionRequest\x12'\n" +
+	"\x0fsubscription_id\x18\x01 \x01(\tR\x0esubscriptionId\x12\x1d\n" +
+	"\n" +
+	"add_topics\x18\x02 \x03(\tR\taddTopics\x12#\n" +
+	"\rremove_topics\x18\x03 \x03(\tR\fremoveTopics\"4\n" +
+	"\x1aUpdateSubscriptionResponse\x12\x16\n" +
+	"\x06topics\x18\x01 \x03(\tR\x06topics\"J\n" +
+	"\n" +
+	"AckRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x14\n" +
+	"\x05group\x18\x02 \x01(\tR\x05group\x12\x10\n" +
+	"\x03ids\x18\x03 \x03(\tR\x03ids\".\n" +
+	"\vAckResponse\x12\x1f\n" +
+	"\vacked_count\x18\x01 \x01(\x05R\n" +
+	"ackedCount\"a\n" +
+	"\vNackRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x14\n" +
+	"\x05group\x18\x02 \x01(\tR\x05group\x12\x10\n" +
+	"\x03ids\x18\x03 \x03(\tR\x03ids\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"1\n" +
+	"\fNackResponse\x12!\n" +
+	"\fnacked_count\x18\x01 \x01(\x05R\vnackedCount\"-\n" +
+	"\x11ListTopicsRequest\x12\x18\n" +
+	"\apattern\x18\x01 \x01(\tR\apattern\",\n" +
+	"\x12ListTopicsResponse\x12\x16\n" +
+	"\x06topics\x18\x01 \x03(\tR\x06topics\",\n" +
+	"\x14GetTopicStatsRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\"\xb5\x02\n" +
+	"\n" +
+	"TopicStats\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12+\n" +
+	"\x11local_subscribers\x18\x02 \x01(\x03R\x10localSubscribers\x12+\n" +
+	"\x11redis_subscribers\x18\x03 \x01(\x03R\x10redisSubscribers\x12'\n" +
+	"\x0fpublished_count\x18\x04 \x01(\x04R\x0epublishedCount\x12!\n" +
+	"\fpublish_rate\x18\x05 \x01(\x01R\vpublishRate\x12F\n" +
+	"\x11last_message_time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x0flastMessageTime\x12#\n" +
+	"\rstream_length\x18\a \x01(\x03R\fstreamLength\"*\n" +
+	"\x12DeleteTopicRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\"/\n" +
+	"\x13DeleteTopicResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\bR\adeleted\"q\n" +
+	"\x0eRedriveRequest\x12*\n" +
+	"\x11dead_letter_topic\x18\x01 \x01(\tR\x0fdeadLetterTopic\x12\x10\n" +
+	"\x03ids\x18\x02 \x03(\tR\x03ids\x12!\n" +
+	"\fmax_messages\x18\x03 \x01(\x05R\vmaxMessages\"8\n" +
+	"\x0fRedriveResponse\x12%\n" +
+	"\x0eredriven_count\x18\x01 \x01(\x05R\rredrivenCount\",\n" +
+	"\x14ClearRetainedRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\"1\n" +
+	"\x15ClearRetainedResponse\x12\x18\n" +
+	"\acleared\x18\x01 \x01(\bR\acleared\"\xa0\x02\n" +
+	"\x0eRequestRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12=\n" +
+	"\aheaders\x18\x02 \x03(\v2#.pubsub.RequestRequest.HeadersEntryR\aheaders\x12!\n" +
+	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\x12\x18\n" +
+	"\apayload\x18\x04 \x01(\fR\apayload\x12!\n" +
+	"\fpublisher_id\x18\x05 \x01(\tR\vpublisherId\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x06 \x01(\x03R\ttimeoutMs\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"~\n" +
+	"\x0fRequestResponse\x12%\n" +
+	"\x05reply\x18\x01 \x01(\v2\x0f.pubsub.MessageR\x05reply\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x02 \x01(\tR\trequestId\x12%\n" +
+	"\x0ecorrelation_id\x18\x03 \x01(\tR\rcorrelationId\"\xb4\x02\n" +
+	"\x10PushSubscription\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05topic\x18\x02 \x01(\tR\x05topic\x12\x1a\n" +
+	"\bendpoint\x18\x03 \x01(\tR\bendpoint\x12\x16\n" +
+	"\x06secret\x18\x04 \x01(\tR\x06secret\x12\x16\n" +
+	"\x06filter\x18\x05 \x01(\tR\x06filter\x122\n" +
+	"\x15max_delivery_attempts\x18\x06 \x01(\x05R\x13maxDeliveryAttempts\x12$\n" +
+	"\x0emin_backoff_ms\x18\a \x01(\x03R\fminBackoffMs\x12$\n" +
+	"\x0emax_backoff_ms\x18\b \x01(\x03R\fmaxBackoffMs\x12*\n" +
+	"\x11dead_letter_topic\x18\t \x01(\tR\x0fdeadLetterTopic\"]\n" +
+	"\x1dCreatePushSubscriptionRequest\x12<\n" +
+	"\fsubscription\x18\x01 \x01(\v2\x18.pubsub.PushSubscriptionR\fsubscription\"3\n" +
+	"\x1dDeletePushSubscriptionRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\":\n" +
+	"\x1eDeletePushSubscriptionResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\bR\adeleted\"\x1e\n" +
+	"\x1cListPushSubscriptionsRequest\"_\n" +
+	"\x1dListPushSubscriptionsResponse\x12>\n" +
+	"\rsubscriptions\x18\x01 \x03(\v2\x18.pubsub.PushSubscriptionR\rsubscriptions*p\n" +
+	"\rStartPosition\x12\x10\n" +
+	"\fSTART_LATEST\x10\x00\x12\x12\n" +
+	"\x0eSTART_EARLIEST\x10\x01\x12\x12\n" +
+	"\x0eSTART_AFTER_ID\x10\x02\x12\x13\n" +
+	"\x0fSTART_FROM_TIME\x10\x03\x12\x10\n" +
+	"\fSTART_LAST_N\x10\x04*\x87\x01\n" +
+	"\x0eOverflowPolicy\x12\x14\n" +
+	"\x10OVERFLOW_DEFAULT\x10\x00\x12\x12\n" +
+	"\x0eOVERFLOW_BLOCK\x10\x01\x12\x18\n" +
+	"\x14OVERFLOW_DROP_OLDEST\x10\x02\x12\x18\n" +
+	"\x14OVERFLOW_DROP_NEWEST\x10\x03\x12\x17\n" +
+	"\x13OVERFLOW_DISCONNECT\x10\x042\xcb\b\n" +
+	"\x06PubSub\x12<\n" +
+	"\aPublish\x12\x16.pubsub.PublishRequest\x1a\x17.pubsub.PublishResponse(\x01\x12?\n" +
+	"\rPublishStream\x12\x16.pubsub.PublishRequest\x1a\x12.pubsub.PublishAck(\x010\x01\x12B\n" +
+	"\tSubscribe\x12\x18.pubsub.SubscribeRequest\x1a\x19.pubsub.SubscribeResponse0\x01\x12.\n" +
+	"\x03Ack\x12\x12.pubsub.AckRequest\x1a\x13.pubsub.AckResponse\x121\n" +
+	"\x04Nack\x12\x13.pubsub.NackRequest\x1a\x14.pubsub.NackResponse\x12[\n" +
+	"\x12UpdateSubscription\x12!.pubsub.UpdateSubscriptionRequest\x1a\".pubsub.UpdateSubscriptionResponse\x12C\n" +
+	"\n" +
+	"ListTopics\x12\x19.pubsub.ListTopicsRequest\x1a\x1a.pubsub.ListTopicsResponse\x12A\n" +
+	"\rGetTopicStats\x12\x1c.pubsub.GetTopicStatsRequest\x1a\x12.pubsub.TopicStats\x12F\n" +
+	"\vDeleteTopic\x12\x1a.pubsub.DeleteTopicRequest\x1a\x1b.pubsub.DeleteTopicResponse\x12:\n" +
+	"\aRedrive\x12\x16.pubsub.RedriveRequest\x1a\x17.pubsub.RedriveResponse\x12L\n" +
+	"\rClearRetained\x12\x1c.pubsub.ClearRetainedRequest\x1a\x1d.pubsub.ClearRetainedResponse\x12:\n" +
+	"\aRequest\x12\x16.pubsub.RequestRequest\x1a\x17.pubsub.RequestResponse\x12Y\n" +
+	"\x16CreatePushSubscription\x12%.pubsub.CreatePushSubscriptionRequest\x1a\x18.pubsub.PushSubscription\x12g\n" +
+	"\x16DeletePushSubscription\x12%.pubsub.DeletePushSubscriptionRequest\x1a&.pubsub.DeletePushSubscriptionResponse\x12d\n" +
+	"\x15ListPushSubscriptions\x12$.pubsub.ListPushSubscriptionsRequest\x1a%.pubsub.ListPushSubscriptionsResponseB\x10Z\x0e./proto/pubsubb\x06proto3"
 
 var (
 	file_pubsub_proto_rawDescOnce sync.Once
@@ -261,23 +2355,96 @@ func file_pubsub_proto_rawDescGZIP() []byte {
 	return file_pubsub_proto_rawDescData
 }
 
-var file_pubsub_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_pubsub_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_pubsub_proto_msgTypes = make([]protoimpl.MessageInfo, 33)
 var file_pubsub_proto_goTypes = []any{
-	(*PublishRequest)(nil),    // 0: pubsub.PublishRequest
-	(*PublishResponse)(nil),   // 1: pubsub.PublishResponse
-	(*SubscribeRequest)(nil),  // 2: pubsub.SubscribeRequest
-	(*SubscribeResponse)(nil), // 3: pubsub.SubscribeResponse
+	(StartPosition)(0),                     // 0: pubsub.StartPosition
+	(OverflowPolicy)(0),                    // 1: pubsub.OverflowPolicy
+	(*Message)(nil),                        // 2: pubsub.Message
+	(*PublishRequest)(nil),                 // 3: pubsub.PublishRequest
+	(*PublishAck)(nil),                     // 4: pubsub.PublishAck
+	(*PublishResponse)(nil),                // 5: pubsub.PublishResponse
+	(*SubscribeRequest)(nil),               // 6: pubsub.SubscribeRequest
+	(*SubscribeResponse)(nil),              // 7: pubsub.SubscribeResponse
+	(*UpdateSubscriptionRequest)(nil),      // 8: pubsub.UpdateSubscriptionRequest
+	(*UpdateSubscriptionResponse)(nil),     // 9: pubsub.UpdateSubscriptionResponse
+	(*AckRequest)(nil),                     // 10: pubsub.AckRequest
+	(*AckResponse)(nil),                    // 11: pubsub.AckResponse
+	(*NackRequest)(nil),                    // 12: pubsub.NackRequest
+	(*NackResponse)(nil),                   // 13: pubsub.NackResponse
+	(*ListTopicsRequest)(nil),              // 14: pubsub.ListTopicsRequest
+	(*ListTopicsResponse)(nil),             // 15: pubsub.ListTopicsResponse
+	(*GetTopicStatsRequest)(nil),           // 16: pubsub.GetTopicStatsRequest
+	(*TopicStats)(nil),                     // 17: pubsub.TopicStats
+	(*DeleteTopicRequest)(nil),             // 18: pubsub.DeleteTopicRequest
+	(*DeleteTopicResponse)(nil),            // 19: pubsub.DeleteTopicResponse
+	(*RedriveRequest)(nil),                 // 20: pubsub.RedriveRequest
+	(*RedriveResponse)(nil),                // 21: pubsub.RedriveResponse
+	(*ClearRetainedRequest)(nil),           // 22: pubsub.ClearRetainedRequest
+	(*ClearRetainedResponse)(nil),          // 23: pubsub.ClearRetainedResponse
+	(*RequestRequest)(nil),                 // 24: pubsub.RequestRequest
+	(*RequestResponse)(nil),                // 25: pubsub.RequestResponse
+	(*PushSubscription)(nil),               // 26: pubsub.PushSubscription
+	(*CreatePushSubscriptionRequest)(nil),  // 27: pubsub.CreatePushSubscriptionRequest
+	(*DeletePushSubscriptionRequest)(nil),  // 28: pubsub.DeletePushSubscriptionRequest
+	(*DeletePushSubscriptionResponse)(nil), // 29: pubsub.DeletePushSubscriptionResponse
+	(*ListPushSubscriptionsRequest)(nil),   // 30: pubsub.ListPushSubscriptionsRequest
+	(*ListPushSubscriptionsResponse)(nil),  // 31: pubsub.ListPushSubscriptionsResponse
+	nil,                                    // 32: pubsub.Message.HeadersEntry
+	nil,                                    // 33: pubsub.PublishRequest.HeadersEntry
+	nil,                                    // 34: pubsub.RequestRequest.HeadersEntry
+	(*timestamppb.Timestamp)(nil),          // 35: google.protobuf.Timestamp
 }
 var file_pubsub_proto_depIdxs = []int32{
-	0, // 0: pubsub.PubSub.Publish:input_type -> pubsub.PublishRequest
-	2, // 1: pubsub.PubSub.Subscribe:input_type -> pubsub.SubscribeRequest
-	1, // 2: pubsub.PubSub.Publish:output_type -> pubsub.PublishResponse
-	3, // 3: pubsub.PubSub.Subscribe:output_type -> pubsub.SubscribeResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	35, // 0: pubsub.Message.publish_time:type_name -> google.protobuf.Timestamp
+	32, // 1: pubsub.Message.headers:type_name -> pubsub.Message.HeadersEntry
+	35, // 2: pubsub.Message.deliver_at:type_name -> google.protobuf.Timestamp
+	33, // 3: pubsub.PublishRequest.headers:type_name -> pubsub.PublishRequest.HeadersEntry
+	35, // 4: pubsub.PublishRequest.deliver_at:type_name -> google.protobuf.Timestamp
+	0,  // 5: pubsub.SubscribeRequest.start_position:type_name -> pubsub.StartPosition
+	35, // 6: pubsub.SubscribeRequest.start_time:type_name -> google.protobuf.Timestamp
+	1,  // 7: pubsub.SubscribeRequest.overflow_policy:type_name -> pubsub.OverflowPolicy
+	2,  // 8: pubsub.SubscribeResponse.envelope:type_name -> pubsub.Message
+	35, // 9: pubsub.TopicStats.last_message_time:type_name -> google.protobuf.Timestamp
+	34, // 10: pubsub.RequestRequest.headers:type_name -> pubsub.RequestRequest.HeadersEntry
+	2,  // 11: pubsub.RequestResponse.reply:type_name -> pubsub.Message
+	26, // 12: pubsub.CreatePushSubscriptionRequest.subscription:type_name -> pubsub.PushSubscription
+	26, // 13: pubsub.ListPushSubscriptionsResponse.subscriptions:type_name -> pubsub.PushSubscription
+	3,  // 14: pubsub.PubSub.Publish:input_type -> pubsub.PublishRequest
+	3,  // 15: pubsub.PubSub.PublishStream:input_type -> pubsub.PublishRequest
+	6,  // 16: pubsub.PubSub.Subscribe:input_type -> pubsub.SubscribeRequest
+	10, // 17: pubsub.PubSub.Ack:input_type -> pubsub.AckRequest
+	12, // 18: pubsub.PubSub.Nack:input_type -> pubsub.NackRequest
+	8,  // 19: pubsub.PubSub.UpdateSubscription:input_type -> pubsub.UpdateSubscriptionRequest
+	14, // 20: pubsub.PubSub.ListTopics:input_type -> pubsub.ListTopicsRequest
+	16, // 21: pubsub.PubSub.GetTopicStats:input_type -> pubsub.GetTopicStatsRequest
+	18, // 22: pubsub.PubSub.DeleteTopic:input_type -> pubsub.DeleteTopicRequest
+	20, // 23: pubsub.PubSub.Redrive:input_type -> pubsub.RedriveRequest
+	22, // 24: pubsub.PubSub.ClearRetained:input_type -> pubsub.ClearRetainedRequest
+	24, // 25: pubsub.PubSub.Request:input_type -> pubsub.RequestRequest
+	27, // 26: pubsub.PubSub.CreatePushSubscription:input_type -> pubsub.CreatePushSubscriptionRequest
+	28, // 27: pubsub.PubSub.DeletePushSubscription:input_type -> pubsub.DeletePushSubscriptionRequest
+	30, // 28: pubsub.PubSub.ListPushSubscriptions:input_type -> pubsub.ListPushSubscriptionsRequest
+	5,  // 29: pubsub.PubSub.Publish:output_type -> pubsub.PublishResponse
+	4,  // 30: pubsub.PubSub.PublishStream:output_type -> pubsub.PublishAck
+	7,  // 31: pubsub.PubSub.Subscribe:output_type -> pubsub.SubscribeResponse
+	11, // 32: pubsub.PubSub.Ack:output_type -> pubsub.AckResponse
+	13, // 33: pubsub.PubSub.Nack:output_type -> pubsub.NackResponse
+	9,  // 34: pubsub.PubSub.UpdateSubscription:output_type -> pubsub.UpdateSubscriptionResponse
+	15, // 35: pubsub.PubSub.ListTopics:output_type -> pubsub.ListTopicsResponse
+	17, // 36: pubsub.PubSub.GetTopicStats:output_type -> pubsub.TopicStats
+	19, // 37: pubsub.PubSub.DeleteTopic:output_type -> pubsub.DeleteTopicResponse
+	21, // 38: pubsub.PubSub.Redrive:output_type -> pubsub.RedriveResponse
+	23, // 39: pubsub.PubSub.ClearRetained:output_type -> pubsub.ClearRetainedResponse
+	25, // 40: pubsub.PubSub.Request:output_type -> pubsub.RequestResponse
+	26, // 41: pubsub.PubSub.CreatePushSubscription:output_type -> pubsub.PushSubscription
+	29, // 42: pubsub.PubSub.DeletePushSubscription:output_type -> pubsub.DeletePushSubscriptionResponse
+	31, // 43: pubsub.PubSub.ListPushSubscriptions:output_type -> pubsub.ListPushSubscriptionsResponse
+	29, // [29:44] is the sub-list for method output_type
+	14, // [14:29] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
 }
 
 func init() { file_pubsub_proto_init() }
@@ -290,13 +2457,14 @@ func file_pubsub_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pubsub_proto_rawDesc), len(file_pubsub_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      2,
+			NumMessages:   33,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_pubsub_proto_goTypes,
 		DependencyIndexes: file_pubsub_proto_depIdxs,
+		EnumInfos:         file_pubsub_proto_enumTypes,
 		MessageInfos:      file_pubsub_proto_msgTypes,
 	}.Build()
 	File_pubsub_proto = out.File
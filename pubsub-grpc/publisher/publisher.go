@@ -1,19 +1,32 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 
+	"pubsub/auth"
 	pb "pubsub/proto/pubsub"
+	"pubsub/responder"
 )
 
 // 生成有意义的消息
@@ -30,7 +43,571 @@ func generateMeaningfulMessage(topic string, index int) string {
 	}
 }
 
-func publishMessages(client pb.PubSubClient) {
+// maxPublishAttempts 单条消息最多发布的次数（含首次）
+const maxPublishAttempts = 3
+
+// defaultCount 未指定 -count 时生成的消息和 -message 发布的条数
+const defaultCount = 10
+
+// 发布流断开后重新建立的等待时间，连续 maxStreamFailures 个发布流都没有收到确认时放弃
+const (
+	streamMinBackoff  = 200 * time.Millisecond
+	streamMaxBackoff  = 5 * time.Second
+	maxStreamFailures = 6
+)
+
+// headerFlags 可重复指定的 -header key=value 参数
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	pairs := make([]string, 0, len(h))
+	for k, v := range h {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (h headerFlags) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("消息头格式应为 key=value: %s", s)
+	}
+	h[k] = v
+	return nil
+}
+
+// sourceMessage 从消息来源读取的一条消息
+type sourceMessage struct {
+	topic       string // 为空时发布到 -topic 指定的每个主题
+	key         string // 幂等键，为空时使用生产者 ID 和序列号
+	orderingKey string // 排序键，为空时使用 -ordering-key
+	retain      bool   // 保留消息
+	headers     map[string]string
+	contentType string
+	payload     []byte
+	generated   bool // 内容由 generateMeaningfulMessage 按主题生成
+}
+
+// jsonMessage JSON Lines 格式中的一行
+type jsonMessage struct {
+	Topic          string            `json:"topic"`
+	IdempotencyKey string            `json:"idempotency_key"`
+	OrderingKey    string            `json:"ordering_key"`
+	Retain         bool              `json:"retain"`
+	Headers        map[string]string `json:"headers"`
+	ContentType    string            `json:"content_type"`
+	Payload        string            `json:"payload"`
+}
+
+// messageSource 依次返回要发布的消息，没有更多消息时返回 io.EOF
+type messageSource func() (*sourceMessage, error)
+
+// generatedSource 生成示例消息
+func generatedSource() messageSource {
+	return func() (*sourceMessage, error) {
+		return &sourceMessage{contentType: "text/plain", generated: true}, nil
+	}
+}
+
+// textSource 重复发布同一条消息
+func textSource(text, contentType string) messageSource {
+	return func() (*sourceMessage, error) {
+		return &sourceMessage{contentType: contentType, payload: []byte(text)}, nil
+	}
+}
+
+// lineSource 逐行读取消息，format 为 text 时每行是消息内容，为 json 时每行是一个 jsonMessage
+func lineSource(r io.Reader, format, contentType string) messageSource {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	return func() (*sourceMessage, error) {
+		for scanner.Scan() {
+			line++
+			text := scanner.Text()
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			if format == "text" {
+				return &sourceMessage{contentType: contentType, payload: []byte(text)}, nil
+			}
+
+			var jm jsonMessage
+			if err := json.Unmarshal([]byte(text), &jm); err != nil {
+				return nil, fmt.Errorf("第 %d 行不是有效的 JSON: %v", line, err)
+			}
+			if jm.ContentType == "" {
+				jm.ContentType = contentType
+			}
+			return &sourceMessage{topic: jm.Topic, key: jm.IdempotencyKey, orderingKey: jm.OrderingKey, retain: jm.Retain, headers: jm.Headers, contentType: jm.ContentType, payload: []byte(jm.Payload)}, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+}
+
+// rateLimiter 限制所有发布流合计的发送速率
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter 创建每秒最多 rate 条的限速器，rate 为 0 时不限速
+func newRateLimiter(rate float64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait 等待下一个发送时机
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// publishStats 汇总所有发布流的结果
+type publishStats struct {
+	mu        sync.Mutex
+	sent      int
+	acked     int
+	scheduled int
+	duplicate int
+	failed    int
+	latencies []time.Duration // 从首次发送到收到成功确认的时间
+}
+
+func (st *publishStats) addSent() {
+	st.mu.Lock()
+	st.sent++
+	st.mu.Unlock()
+}
+
+func (st *publishStats) addAck(latency time.Duration, ack *pb.PublishAck) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.acked++
+	if ack.Scheduled {
+		st.scheduled++
+	}
+	if ack.Duplicate {
+		st.duplicate++
+	}
+	st.latencies = append(st.latencies, latency)
+}
+
+func (st *publishStats) addFailed() {
+	st.mu.Lock()
+	st.failed++
+	st.mu.Unlock()
+}
+
+// percentile 返回已排序延迟的第 p 百分位
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// print 打印发布汇总和确认延迟分位数
+func (st *publishStats) print(elapsed time.Duration) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	latencies := append([]time.Duration(nil), st.latencies...)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	fmt.Println("发布汇总:")
+	fmt.Printf("  已发送: %d\n", st.sent)
+	fmt.Printf("  已确认: %d (其中延迟投递 %d，重复 %d)\n", st.acked, st.scheduled, st.duplicate)
+	fmt.Printf("  失败: %d\n", st.failed)
+	fmt.Printf("  未确认: %d\n", st.sent-st.acked-st.failed)
+	fmt.Printf("  耗时: %v\n", elapsed.Round(time.Millisecond))
+	if elapsed > 0 {
+		fmt.Printf("  吞吐: %.1f 条/秒\n", float64(st.acked)/elapsed.Seconds())
+	}
+	if len(latencies) > 0 {
+		fmt.Printf("  确认延迟: p50=%v p90=%v p99=%v max=%v\n",
+			percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99), latencies[len(latencies)-1])
+	}
+}
+
+// retryable 判断发布失败的消息是否值得重试，参数错误和权限不足时重试也不会成功
+func retryable(code codes.Code) bool {
+	switch code {
+	case codes.InvalidArgument, codes.PermissionDenied, codes.Unauthenticated:
+		return false
+	}
+	return true
+}
+
+// outgoing 待发布的消息及其在消息来源中的序号
+type outgoing struct {
+	req   *pb.PublishRequest
+	index int
+}
+
+// pendingMessage 已发送但尚未收到成功确认的消息
+type pendingMessage struct {
+	req       *pb.PublishRequest
+	index     int
+	attempts  int
+	firstSent time.Time
+}
+
+// publishWorker 通过双向流发布 msgs 中的消息，逐条等待确认并只重试失败的消息。
+// 发布流断开时重新建立，未确认的消息在新的发布流上重新发送，
+// 连续 maxStreamFailures 个发布流都没有收到确认时放弃
+func publishWorker(ctx context.Context, client pb.PubSubClient, worker int, msgs <-chan *outgoing, stats *publishStats) {
+	var (
+		unacked  []*pendingMessage
+		failures int
+		retry    = streamMinBackoff
+	)
+	for {
+		started := time.Now()
+		var (
+			acked bool
+			err   error
+		)
+		unacked, acked, err = publishStream(ctx, client, worker, msgs, unacked, stats)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		if acked {
+			failures = 0
+		}
+		if failures++; failures >= maxStreamFailures {
+			log.Printf("发布流 %d 连续 %d 次断开，放弃发布，%d 条消息未确认: %v", worker, failures, len(unacked), err)
+			return
+		}
+		// 发布流运行过一段时间后才断开的，从最短的等待时间开始重新建立
+		if time.Since(started) > streamMaxBackoff {
+			retry = streamMinBackoff
+		}
+
+		// 重新发送的消息同样计入发布次数
+		kept := unacked[:0]
+		for _, pm := range unacked {
+			if pm.attempts >= maxPublishAttempts {
+				stats.addFailed()
+				log.Printf("主题 %s 的消息 #%d 发布失败 (发布流断开)，已放弃", pm.req.Topic, pm.index)
+				continue
+			}
+			kept = append(kept, pm)
+		}
+		unacked = kept
+
+		log.Printf("发布流 %d 已断开，%v 后重新建立并重新发送 %d 条未确认的消息: %v", worker, retry, len(unacked), err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry = min(retry*2, streamMaxBackoff)
+	}
+}
+
+// publishStream 建立一个发布流，先重新发送上一个发布流未确认的消息，再发布 msgs 中的消息。
+// msgs 读完且全部消息确认或放弃后返回 nil；发布流断开时返回错误和按发送顺序排列的未确认消息，
+// acked 表示该发布流是否收到过确认
+func publishStream(ctx context.Context, client pb.PubSubClient, worker int, msgs <-chan *outgoing, resend []*pendingMessage, stats *publishStats) (unacked []*pendingMessage, acked bool, err error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := client.PublishStream(streamCtx)
+	if err != nil {
+		return resend, false, fmt.Errorf("创建发布流失败: %w", err)
+	}
+
+	var (
+		mu       sync.Mutex
+		pending  = make(map[int64]*pendingMessage)
+		retryCh  = make(chan *pendingMessage, 10)
+		settleCh = make(chan struct{}, 1)
+		recvDone = make(chan struct{})
+		recvErr  error
+		sequence int64
+	)
+
+	// 接收确认：成功的消息从待确认列表移除，失败的消息放入重试队列
+	go func() {
+		defer close(recvDone)
+		for {
+			ack, err := stream.Recv()
+			if err != nil {
+				if err == io.EOF {
+					err = errors.New("服务端关闭了发布流")
+				}
+				recvErr = err
+				return
+			}
+
+			mu.Lock()
+			pm, ok := pending[ack.Sequence]
+			retry := false
+			switch {
+			case !ok:
+			case ack.Error == "":
+				delete(pending, ack.Sequence)
+				acked = true
+				stats.addAck(time.Since(pm.firstSent), ack)
+				switch {
+				case ack.Duplicate:
+					log.Printf("主题 %s 的消息 #%d 是重复消息，服务端未再次发布，首次发布的消息 ID: %s", pm.req.Topic, pm.index, ack.MessageId)
+				case ack.Scheduled:
+					log.Printf("主题 %s 的消息 #%d 已保存，将延迟投递，消息 ID: %s", pm.req.Topic, pm.index, ack.MessageId)
+				default:
+					log.Printf("主题 %s 的消息 #%d 已确认，消息 ID: %s", pm.req.Topic, pm.index, ack.MessageId)
+				}
+			case pm.attempts < maxPublishAttempts && retryable(codes.Code(ack.Code)):
+				log.Printf("主题 %s 的消息 #%d 发布失败 (%s)，准备重试", pm.req.Topic, pm.index, ack.Error)
+				retry = true
+			default:
+				delete(pending, ack.Sequence)
+				stats.addFailed()
+				log.Printf("主题 %s 的消息 #%d 发布失败 (%s)，已放弃", pm.req.Topic, pm.index, ack.Error)
+			}
+			mu.Unlock()
+
+			// 在锁外放入重试队列，发送方处理重试时需要获取锁
+			if retry {
+				select {
+				case retryCh <- pm:
+				case <-streamCtx.Done():
+					return
+				}
+			}
+			select {
+			case settleCh <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	// broken 结束发布流，等待接收确认结束后按发送顺序返回未确认的消息
+	broken := func(err error) ([]*pendingMessage, bool, error) {
+		cancel()
+		<-recvDone
+		if recvErr != nil && status.Code(recvErr) != codes.Canceled {
+			err = recvErr
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sequences := make([]int64, 0, len(pending))
+		for seq := range pending {
+			sequences = append(sequences, seq)
+		}
+		slices.Sort(sequences)
+		unacked := make([]*pendingMessage, 0, len(sequences))
+		for _, seq := range sequences {
+			unacked = append(unacked, pending[seq])
+		}
+		return unacked, acked, err
+	}
+
+	send := func(pm *pendingMessage) error {
+		pm.attempts++
+		return stream.Send(pm.req)
+	}
+	// enqueue 在本发布流上分配序号并发送消息
+	enqueue := func(pm *pendingMessage) error {
+		sequence++
+		pm.req.Sequence = sequence
+		mu.Lock()
+		pending[sequence] = pm
+		mu.Unlock()
+		return send(pm)
+	}
+	retry := func(pm *pendingMessage) error {
+		time.Sleep(time.Duration(pm.attempts) * 200 * time.Millisecond)
+		return send(pm)
+	}
+
+	// 先重新发送上一个发布流未确认的消息，幂等发布的序列号不变，服务端会丢弃已发布的重复消息
+	for _, pm := range resend {
+		if err := enqueue(pm); err != nil {
+			return broken(err)
+		}
+	}
+
+	// 发送消息，间隙中处理需要重试的消息
+	for msgs != nil {
+		select {
+		case <-ctx.Done():
+			return broken(ctx.Err())
+		case <-recvDone:
+			return broken(recvErr)
+		case pm := <-retryCh:
+			if err := retry(pm); err != nil {
+				return broken(err)
+			}
+		case m, ok := <-msgs:
+			if !ok {
+				msgs = nil
+				break
+			}
+			pm := &pendingMessage{req: m.req, index: m.index, firstSent: time.Now()}
+			if err := enqueue(pm); err != nil {
+				return broken(err)
+			}
+			stats.addSent()
+		}
+	}
+
+	// 等待所有消息确认或放弃
+	for {
+		mu.Lock()
+		remaining := len(pending)
+		mu.Unlock()
+		if remaining == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return broken(ctx.Err())
+		case <-recvDone:
+			return broken(recvErr)
+		case pm := <-retryCh:
+			if err := retry(pm); err != nil {
+				return broken(err)
+			}
+		case <-settleCh:
+		}
+	}
+
+	// 完成发送，关闭流
+	if err := stream.CloseSend(); err != nil {
+		log.Printf("关闭流时出错: %v", err)
+	}
+	<-recvDone
+	return nil, acked, nil
+}
+
+// publishOptions 发布参数
+type publishOptions struct {
+	topics      []string
+	publisherID string
+	producerID  string // 幂等发布的生产者 ID，为空时不使用幂等发布
+	orderingKey string // 默认排序键
+	retain      bool   // 所有消息都作为保留消息发布
+	headers     map[string]string
+	delay       time.Duration
+	count       int           // 从消息来源读取的条数，0 表示读到结尾
+	duration    time.Duration // 发布的最长时间，0 表示不限制
+	rate        float64       // 每秒最多发送的条数，0 表示不限速
+	concurrency int           // 并发的发布流数量
+}
+
+// produce 按速率从消息来源读取消息，为每个主题生成发布请求，直到达到条数、时长或来源结束
+func produce(ctx context.Context, source messageSource, opts publishOptions, msgs chan<- *outgoing) error {
+	defer close(msgs)
+
+	if opts.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.duration)
+		defer cancel()
+	}
+	limiter := newRateLimiter(opts.rate)
+	var sequence int64
+
+	for i := 1; opts.count == 0 || i <= opts.count; i++ {
+		m, err := source()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		topics := opts.topics
+		if m.topic != "" {
+			topics = []string{m.topic}
+		}
+		if len(topics) == 0 {
+			return fmt.Errorf("第 %d 条消息没有指定主题", i)
+		}
+		for _, topic := range topics {
+			if limiter.wait(ctx) != nil {
+				return nil
+			}
+			payload := m.payload
+			headers := make(map[string]string)
+			if m.generated {
+				// 生成的测试消息带有 index 消息头，便于订阅端按序号过滤
+				payload = []byte(generateMeaningfulMessage(topic, i))
+				headers["index"] = strconv.Itoa(i)
+			}
+			for k, v := range opts.headers {
+				headers[k] = v
+			}
+			for k, v := range m.headers {
+				headers[k] = v
+			}
+			req := &pb.PublishRequest{
+				Topic:       topic,
+				PublisherId: opts.publisherID,
+				Headers:     headers,
+				ContentType: m.contentType,
+				Payload:     payload,
+				DelayMs:     opts.delay.Milliseconds(),
+				OrderingKey: m.orderingKey,
+				Retain:      m.retain || opts.retain,
+			}
+			if req.OrderingKey == "" {
+				req.OrderingKey = opts.orderingKey
+			}
+			// 幂等发布：序列号在重试时保持不变，服务端丢弃去重窗口内的重复消息
+			switch {
+			case m.key != "":
+				req.IdempotencyKey = m.key
+			case opts.producerID != "":
+				sequence++
+				req.ProducerId = opts.producerID
+				req.ProducerSequence = sequence
+			}
+			select {
+			case msgs <- &outgoing{req: req, index: i}:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// publishMessages 启动发布流并发布消息来源中的全部消息，结束后打印汇总
+func publishMessages(client pb.PubSubClient, source messageSource, opts publishOptions) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -43,73 +620,181 @@ func publishMessages(client pb.PubSubClient) {
 		cancel()
 	}()
 
-	topics := []string{"topic1", "topic2", "topic3"}
-	var wg sync.WaitGroup
+	log.Printf("开始向主题 %v 发布消息 (%d 个发布流)", opts.topics, opts.concurrency)
 
-	// 为每个主题创建一个协程发布消息
-	for _, topic := range topics {
+	started := time.Now()
+	stats := &publishStats{}
+	msgs := make(chan *outgoing)
+	var wg sync.WaitGroup
+	for i := 1; i <= opts.concurrency; i++ {
 		wg.Add(1)
-		go func(topic string) {
+		go func(worker int) {
 			defer wg.Done()
+			publishWorker(ctx, client, worker, msgs, stats)
+		}(i)
+	}
 
-			// 创建流
-			stream, err := client.Publish(ctx)
-			if err != nil {
-				log.Fatalf("创建发布流失败: %v", err)
-			}
+	// 所有发布流都提前退出时停止读取消息
+	workersDone := make(chan struct{})
+	produceCtx, stopProduce := context.WithCancel(ctx)
+	defer stopProduce()
+	go func() {
+		wg.Wait()
+		close(workersDone)
+		stopProduce()
+	}()
 
-			log.Printf("开始向主题 %s 发布消息...", topic)
+	if err := produce(produceCtx, source, opts, msgs); err != nil {
+		log.Printf("读取消息失败: %v", err)
+	}
 
-			// 发送10条消息到指定主题
-			for i := 1; i <= 10; i++ {
-				select {
-				case <-ctx.Done():
-					// 上下文被取消，结束发送
-					return
-				default:
-					// 生成有意义的消息
-					message := generateMeaningfulMessage(topic, i)
-
-					// 发送消息
-					req := &pb.PublishRequest{
-						Topic:   topic,
-						Message: message,
-					}
-					if err := stream.Send(req); err != nil {
-						log.Printf("发送消息失败: %v", err)
-						return
-					}
-
-					log.Printf("已发送消息到主题 %s: %s", topic, message)
-
-					// 短暂等待
-					time.Sleep(500 * time.Millisecond)
-				}
-			}
+	// 等待所有发布完成
+	<-workersDone
+	stats.print(time.Since(started))
+}
 
-			// 完成发送，关闭流
-			resp, err := stream.CloseAndRecv()
-			if err != nil {
-				log.Printf("关闭流时出错: %v", err)
-			} else {
-				log.Printf("向主题 %s 发布结束。成功: %v, 消息数: %d",
-					topic, resp.Success, resp.MessageCount)
+// requestMessages 将每条消息作为请求依次发送，等待响应并把响应内容逐行写到标准输出
+func requestMessages(client pb.PubSubClient, source messageSource, opts publishOptions, timeout time.Duration) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// 请求不使用幂等发布和延迟投递
+	opts.producerID = ""
+	opts.delay = 0
+	msgs := make(chan *outgoing)
+	go func() {
+		if err := produce(ctx, source, opts, msgs); err != nil {
+			log.Printf("读取消息失败: %v", err)
+		}
+	}()
+
+	var ok, failed int
+	for m := range msgs {
+		req := m.req
+		started := time.Now()
+		resp, err := client.Request(ctx, &pb.RequestRequest{
+			Topic:       req.Topic,
+			Headers:     req.Headers,
+			ContentType: req.ContentType,
+			Payload:     req.Payload,
+			PublisherId: req.PublisherId,
+			TimeoutMs:   timeout.Milliseconds(),
+		})
+		if err == nil {
+			err = responder.ReplyError(resp.Reply)
+		}
+		if err != nil {
+			failed++
+			log.Printf("请求主题 %s 失败: %v", req.Topic, err)
+			if ctx.Err() != nil {
+				break
 			}
-		}(topic)
+			continue
+		}
+		ok++
+		log.Printf("收到主题 %s 的响应 (请求 %s, 耗时 %v)", req.Topic, resp.RequestId, time.Since(started).Round(time.Millisecond))
+		fmt.Printf("%s\n", resp.Reply.Payload)
 	}
-
-	// 等待所有发布完成
-	wg.Wait()
-	log.Println("所有主题的消息发布完成")
+	log.Printf("请求完成: %d 成功, %d 失败", ok, failed)
 }
 
 func main() {
-	conn, err := grpc.NewClient("localhost:1234", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	headers := headerFlags{}
+	addr := flag.String("addr", "localhost:1234", "PubSub 服务地址")
+	token := flag.String("token", os.Getenv("PUBSUB_TOKEN"), "访问令牌，服务端启用 ACL 时需要，默认读取环境变量 PUBSUB_TOKEN")
+	topic := flag.String("topic", "topic1,topic2,topic3", "发布的主题，多个主题用逗号分隔，每条消息发布到每个主题")
+	message := flag.String("message", "", "消息内容，重复发布 -count 条")
+	file := flag.String("file", "", "逐行读取消息的文件，- 表示标准输入")
+	format := flag.String("format", "text", "-file 的格式: text 每行一条消息，json 每行一个 JSON 对象 {topic, ordering_key, retain, headers, content_type, payload}")
+	contentType := flag.String("content-type", "text/plain", "消息内容类型")
+	flag.Var(headers, "header", "附加到每条消息的消息头 key=value，可重复指定")
+	count := flag.Int("count", 0, fmt.Sprintf("发布的消息条数（每个主题），0 表示 -file 读到结尾、指定 -duration 时不限条数，否则发布 %d 条", defaultCount))
+	duration := flag.Duration("duration", 0, "发布的最长时间，0 表示不限制")
+	rate := flag.Float64("rate", 0, "所有发布流合计每秒最多发送的消息数，0 表示不限速")
+	concurrency := flag.Int("concurrency", 1, "并发的发布流数量")
+	delay := flag.Duration("delay", 0, "延迟投递时间，消息保存在服务端，到期后才发布到主题")
+	idempotent := flag.Bool("idempotent", true, "幂等发布：为每条消息分配生产者序列号，服务端丢弃重复的消息")
+	producerID := flag.String("producer-id", "", "幂等发布的生产者 ID，默认使用发布者 ID；重新运行时使用相同的 ID 可以避免重复发布已发布过的消息")
+	orderingKey := flag.String("ordering-key", "", "消息的排序键，相同排序键的消息在消费者组中按发布顺序投递给同一个订阅者")
+	retain := flag.Bool("retain", false, "作为保留消息发布，服务端保存主题最新的保留消息，新的订阅者订阅时立即收到")
+	request := flag.Bool("request", false, "请求/响应模式：依次发送每条消息并等待响应者的响应，响应内容写到标准输出")
+	timeout := flag.Duration("timeout", 0, "-request 时等待每个响应的时间，0 表示使用服务端默认值")
+	flag.Parse()
+
+	var topics []string
+	for _, t := range strings.Split(*topic, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			topics = append(topics, t)
+		}
+	}
+	if len(topics) == 0 && *format != "json" {
+		log.Fatalf("参数错误: 必须指定主题")
+	}
+	if *concurrency < 1 {
+		log.Fatalf("参数错误: -concurrency 必须大于 0")
+	}
+	if *format != "text" && *format != "json" {
+		log.Fatalf("参数错误: 无效的 -format: %s", *format)
+	}
+
+	var source messageSource
+	switch {
+	case *message != "" && *file != "":
+		log.Fatalf("参数错误: -message 和 -file 只能指定一个")
+	case *message != "":
+		source = textSource(*message, *contentType)
+	case *file == "-":
+		source = lineSource(os.Stdin, *format, *contentType)
+	case *file != "":
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatalf("打开文件失败: %v", err)
+		}
+		defer f.Close()
+		source = lineSource(f, *format, *contentType)
+	default:
+		source = generatedSource()
+	}
+	if *count == 0 && *file == "" && *duration == 0 {
+		*count = defaultCount
+	}
+
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if *token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(auth.TokenCredentials(*token)))
+	}
+	conn, err := grpc.NewClient(*addr, dialOpts...)
 	if err != nil {
 		log.Fatalf("无法连接: %v", err)
 	}
 	defer conn.Close()
 
+	hostname, _ := os.Hostname()
+	publisherID := fmt.Sprintf("publisher-%s-%d", hostname, os.Getpid())
+
+	if !*idempotent {
+		*producerID = ""
+	} else if *producerID == "" {
+		*producerID = publisherID
+	}
+
 	client := pb.NewPubSubClient(conn)
-	publishMessages(client)
+	opts := publishOptions{
+		topics:      topics,
+		publisherID: publisherID,
+		producerID:  *producerID,
+		orderingKey: *orderingKey,
+		retain:      *retain,
+		headers:     headers,
+		delay:       *delay,
+		count:       *count,
+		duration:    *duration,
+		rate:        *rate,
+		concurrency: *concurrency,
+	}
+	if *request {
+		requestMessages(client, source, opts, *timeout)
+		return
+	}
+	publishMessages(client, source, opts)
 }
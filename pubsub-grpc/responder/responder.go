@@ -0,0 +1,122 @@
+// Package responder 实现请求/响应模式中的响应者：订阅请求主题，
+// 处理通过 Request 接口发送的请求消息，并将响应发布到请求指定的回复主题
+package responder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// 请求消息的消息头，与服务端保持一致
+const (
+	HeaderReplyTo       = "x-reply-to"       // 回复主题
+	HeaderCorrelationID = "x-correlation-id" // 关联 ID，响应必须原样带回
+	HeaderReplyError    = "x-reply-error"    // 处理请求失败时响应携带的错误信息
+)
+
+// Reply 请求的响应内容
+type Reply struct {
+	Payload     []byte
+	ContentType string
+	Headers     map[string]string
+}
+
+// Handler 处理一条请求，返回的错误作为 x-reply-error 消息头发送给请求方
+type Handler func(ctx context.Context, req *pb.Message) (*Reply, error)
+
+// Responder 订阅请求主题并逐条处理请求。指定 Group 时多个响应者共同分担请求，
+// 每条请求只由其中一个响应者处理，回复后确认消息
+type Responder struct {
+	Client  pb.PubSubClient
+	Topic   string // 请求主题
+	Group   string // 消费者组（需要服务端持久化模式），为空时每个响应者都会收到请求
+	Handler Handler
+}
+
+// Serve 处理请求直到 ctx 取消或订阅流断开，ctx 取消时返回 nil
+func (r *Responder) Serve(ctx context.Context) error {
+	if r.Client == nil || r.Topic == "" || r.Handler == nil {
+		return errors.New("responder: 必须指定 Client、Topic 和 Handler")
+	}
+	stream, err := r.Client.Subscribe(ctx, &pb.SubscribeRequest{Topic: r.Topic, Group: r.Group})
+	if err != nil {
+		return fmt.Errorf("订阅请求主题失败: %w", err)
+	}
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("接收请求失败: %w", err)
+		}
+		if msg.Heartbeat || msg.Retained || msg.Envelope == nil {
+			continue
+		}
+		if err := r.handle(ctx, msg.Envelope); err != nil {
+			log.Printf("处理请求 %s 失败: %v", msg.Id, err)
+			continue
+		}
+		if r.Group != "" {
+			if _, err := r.Client.Ack(ctx, &pb.AckRequest{Topic: msg.Topic, Group: r.Group, Ids: []string{msg.Id}}); err != nil {
+				log.Printf("确认请求 %s 失败: %v", msg.Id, err)
+			}
+		}
+	}
+}
+
+// handle 处理一条请求并发布响应，没有回复主题的消息不是请求，直接跳过
+func (r *Responder) handle(ctx context.Context, req *pb.Message) error {
+	replyTo := req.Headers[HeaderReplyTo]
+	correlationID := req.Headers[HeaderCorrelationID]
+	if replyTo == "" || correlationID == "" {
+		return nil
+	}
+
+	reply, err := r.Handler(ctx, req)
+	if reply == nil {
+		reply = &Reply{}
+	}
+	headers := make(map[string]string, len(reply.Headers)+2)
+	for k, v := range reply.Headers {
+		headers[k] = v
+	}
+	headers[HeaderCorrelationID] = correlationID
+	if err != nil {
+		headers[HeaderReplyError] = err.Error()
+	}
+	return Publish(ctx, r.Client, &pb.PublishRequest{
+		Topic:       replyTo,
+		Headers:     headers,
+		ContentType: reply.ContentType,
+		Payload:     reply.Payload,
+	})
+}
+
+// Publish 通过 Publish 流发布一条消息
+func Publish(ctx context.Context, client pb.PubSubClient, req *pb.PublishRequest) error {
+	stream, err := client.Publish(ctx)
+	if err != nil {
+		return fmt.Errorf("打开发布流失败: %w", err)
+	}
+	if err := stream.Send(req); err != nil {
+		return fmt.Errorf("发布响应失败: %w", err)
+	}
+	if _, err := stream.CloseAndRecv(); err != nil {
+		return fmt.Errorf("发布响应失败: %w", err)
+	}
+	return nil
+}
+
+// ReplyError 返回响应者处理请求失败时的错误，处理成功时返回 nil
+func ReplyError(reply *pb.Message) error {
+	if msg, ok := reply.GetHeaders()[HeaderReplyError]; ok {
+		return errors.New(msg)
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// rateWindow 计算发布速率的时间窗口，按秒分桶
+const rateWindow = 60
+
+// topicCounter 本服务器上一个主题的发布统计
+type topicCounter struct {
+	published   uint64
+	lastPublish time.Time
+	buckets     [rateWindow]uint64 // 最近每秒的发布数量
+	bucketSecs  [rateWindow]int64  // 每个桶对应的 Unix 秒
+}
+
+// record 记录一次发布
+func (c *topicCounter) record(t time.Time) {
+	sec := t.Unix()
+	i := sec % rateWindow
+	if c.bucketSecs[i] != sec {
+		c.bucketSecs[i] = sec
+		c.buckets[i] = 0
+	}
+	c.buckets[i]++
+	c.published++
+	c.lastPublish = t
+}
+
+// rate 返回最近一分钟的平均发布速率（条/秒）
+func (c *topicCounter) rate(now time.Time) float64 {
+	var total uint64
+	for i, sec := range c.bucketSecs {
+		if now.Unix()-sec < rateWindow {
+			total += c.buckets[i]
+		}
+	}
+	return float64(total) / rateWindow
+}
+
+// topicStats 按主题记录本服务器的发布统计
+type topicStats struct {
+	mu     sync.Mutex
+	topics map[string]*topicCounter
+}
+
+// newTopicStats 创建发布统计
+func newTopicStats() *topicStats {
+	return &topicStats{topics: make(map[string]*topicCounter)}
+}
+
+// record 记录主题的一次发布
+func (ts *topicStats) record(topic string, t time.Time) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	c, ok := ts.topics[topic]
+	if !ok {
+		c = &topicCounter{}
+		ts.topics[topic] = c
+	}
+	c.record(t)
+}
+
+// get 返回主题的发布数、最近一分钟速率和最后发布时间
+func (ts *topicStats) get(topic string) (published uint64, rate float64, last time.Time) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	c, ok := ts.topics[topic]
+	if !ok {
+		return 0, 0, time.Time{}
+	}
+	return c.published, c.rate(time.Now()), c.lastPublish
+}
+
+// names 返回有发布记录的主题
+func (ts *topicStats) names() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	names := make([]string, 0, len(ts.topics))
+	for t := range ts.topics {
+		names = append(names, t)
+	}
+	return names
+}
+
+// forget 删除主题的统计
+func (ts *topicStats) forget(topic string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	delete(ts.topics, topic)
+}
+
+// trackDurable 记录持久化模式下主题的活跃订阅者数量变化
+func (s *pubSubServer) trackDurable(topic string, delta int) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	s.durableSubs[topic] += delta
+	if s.durableSubs[topic] <= 0 {
+		delete(s.durableSubs, topic)
+	}
+}
+
+// localSubscribers 统计本服务器上订阅该主题的订阅者，包括匹配的模式订阅
+func (s *pubSubServer) localSubscribers(topic string) int64 {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	n := int64(s.durableSubs[topic])
+	for _, sub := range s.subscriptions {
+		for _, t := range sub.Topics() {
+			if t == topic || isPattern(t) && matchTopic(t, topic) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// ListTopics 列出消息代理、本服务器订阅和发布记录中出现过的主题
+func (s *pubSubServer) ListTopics(ctx context.Context, req *pb.ListTopicsRequest) (*pb.ListTopicsResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	pattern := req.Pattern
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	seen := make(map[string]bool)
+	add := func(topics ...string) {
+		for _, t := range topics {
+			if !isPattern(t) && matchTopic(pattern, t) {
+				seen[t] = true
+			}
+		}
+	}
+
+	add(s.stats.names()...)
+	s.subMu.Lock()
+	for t := range s.durableSubs {
+		add(t)
+	}
+	for _, sub := range s.subscriptions {
+		add(sub.Topics()...)
+	}
+	s.subMu.Unlock()
+
+	if s.durable {
+		topics, err := s.durableTopics(ctx, pattern)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "查询主题失败: %v", err)
+		}
+		add(topics...)
+	} else if inspector, ok := s.broker.(Inspector); ok {
+		topics, err := inspector.Topics(ctx, pattern)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "查询主题失败: %v", err)
+		}
+		add(topics...)
+	}
+
+	return &pb.ListTopicsResponse{Topics: sortedKeys(seen)}, nil
+}
+
+// durableTopics 返回持久化模式下匹配模式的主题 Stream，启用分区时不包括分区 Stream
+func (s *pubSubServer) durableTopics(ctx context.Context, pattern string) ([]string, error) {
+	var topics []string
+	iter := s.redisClient.ScanType(ctx, 0, pattern, 100, "stream").Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if s.partitions > 1 && isPartitionStream(key) {
+			continue
+		}
+		if !strings.HasPrefix(key, historyKeyPrefix) {
+			topics = append(topics, key)
+		}
+	}
+	return topics, iter.Err()
+}
+
+// GetTopicStats 返回主题的订阅者数量、发布统计和最后一条消息时间
+func (s *pubSubServer) GetTopicStats(ctx context.Context, req *pb.GetTopicStatsRequest) (*pb.TopicStats, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if req.Topic == "" || isPattern(req.Topic) {
+		return nil, status.Error(codes.InvalidArgument, "必须指定一个主题")
+	}
+
+	published, rate, last := s.stats.get(req.Topic)
+	stats := &pb.TopicStats{
+		Topic:            req.Topic,
+		LocalSubscribers: s.localSubscribers(req.Topic),
+		PublishedCount:   published,
+		PublishRate:      rate,
+	}
+
+	if s.durable {
+		length, err := s.redisClient.XLen(ctx, req.Topic).Result()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "查询主题失败: %v", err)
+		}
+		stats.StreamLength = length
+		entries, err := s.redisClient.XRevRangeN(ctx, req.Topic, "+", "-", 1).Result()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "查询主题失败: %v", err)
+		}
+		if len(entries) > 0 {
+			last = latestTime(last, streamEnvelope(req.Topic, entries[0]).PublishTime.AsTime())
+		}
+	} else {
+		if inspector, ok := s.broker.(Inspector); ok {
+			n, err := inspector.Subscribers(ctx, req.Topic)
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "查询订阅数失败: %v", err)
+			}
+			stats.RedisSubscribers = n
+		}
+		// 其他服务器发布的消息只能从保留的历史中得知
+		if replayer, ok := s.broker.(Replayer); ok {
+			history, err := replayer.Replay(ctx, []string{req.Topic}, startPosition{kind: pb.StartPosition_START_LAST_N, lastN: 1})
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "查询历史消息失败: %v", err)
+			}
+			if len(history) > 0 {
+				last = latestTime(last, history[0].PublishTime.AsTime())
+			}
+		}
+	}
+
+	if !last.IsZero() {
+		stats.LastMessageTime = timestamppb.New(last)
+	}
+	return stats, nil
+}
+
+// latestTime 返回较晚的时间
+func latestTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// DeleteTopic 删除持久化主题的 Stream 和分区 Stream，主题的消费者组、未确认消息和保留消息一并删除
+func (s *pubSubServer) DeleteTopic(ctx context.Context, req *pb.DeleteTopicRequest) (*pb.DeleteTopicResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if !s.durable {
+		return nil, status.Error(codes.FailedPrecondition, "只能删除持久化模式下的主题")
+	}
+	if req.Topic == "" || isPattern(req.Topic) || strings.HasPrefix(req.Topic, historyKeyPrefix) {
+		return nil, status.Error(codes.InvalidArgument, "必须指定一个主题")
+	}
+	if s.partitions > 1 && isPartitionStream(req.Topic) {
+		return nil, status.Errorf(codes.InvalidArgument, "%s 是分区 Stream，应删除所属的主题", req.Topic)
+	}
+
+	// 只删除 Stream 类型的键，避免误删 Redis 中的其他数据
+	keyType, err := s.redisClient.Type(ctx, req.Topic).Result()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "查询主题失败: %v", err)
+	}
+	if keyType == "none" {
+		return nil, status.Errorf(codes.NotFound, "主题 %s 不存在", req.Topic)
+	}
+	if keyType != "stream" {
+		return nil, status.Errorf(codes.FailedPrecondition, "键 %s 不是主题 Stream (类型 %s)", req.Topic, keyType)
+	}
+
+	keys := []string{req.Topic}
+	for p := 0; p < s.partitions && s.partitions > 1; p++ {
+		keys = append(keys, partitionStream(req.Topic, p))
+	}
+	n, err := s.redisClient.Del(ctx, keys...).Result()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "删除主题失败: %v", err)
+	}
+	s.redisClient.Del(ctx, groupFiltersKeyPrefix+req.Topic)
+	s.stats.forget(req.Topic)
+	if _, err := s.retained.Clear(ctx, req.Topic); err != nil {
+		log.Printf("清除主题 %s 的保留消息失败: %v", req.Topic, err)
+	}
+	log.Printf("已删除持久化主题 %s", req.Topic)
+	return &pb.DeleteTopicResponse{Deleted: n > 0}, nil
+}
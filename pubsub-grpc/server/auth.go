@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// permission 主题操作的权限
+type permission int
+
+const (
+	permPublish   permission = iota // 发布消息
+	permSubscribe                   // 订阅、确认和否认消息
+)
+
+func (p permission) String() string {
+	if p == permPublish {
+		return "发布"
+	}
+	return "订阅"
+}
+
+// aclPrincipal ACL 文件中的一个调用方，示例：
+//
+//	{"principals": [
+//	  {"name": "orders", "tokens": ["s3cret"], "publish": ["orders.*"], "subscribe": ["orders.*", "billing"]},
+//	  {"name": "ops", "tokens": ["t0ken"], "subscribe": ["*"], "admin": true}
+//	]}
+type aclPrincipal struct {
+	Name      string   `json:"name"`
+	Tokens    []string `json:"tokens"`    // 调用方在 authorization 元数据中携带的令牌
+	Publish   []string `json:"publish"`   // 允许发布的主题，支持 glob 模式
+	Subscribe []string `json:"subscribe"` // 允许订阅的主题，支持 glob 模式
+	Admin     bool     `json:"admin"`     // 允许调用主题管理接口
+}
+
+// allowed 判断调用方是否有主题的权限，topic 为模式时要求它被某个允许的模式覆盖
+func (p *aclPrincipal) allowed(perm permission, topic string) bool {
+	patterns := p.Subscribe
+	if perm == permPublish {
+		patterns = p.Publish
+	}
+	for _, pattern := range patterns {
+		if matchTopic(pattern, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// accessList 解析后的 ACL 文件
+type accessList struct {
+	byToken map[string]*aclPrincipal
+	byName  map[string]*aclPrincipal
+}
+
+// loadACL 读取并校验 ACL 文件
+func loadACL(path string) (*accessList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Principals []*aclPrincipal `json:"principals"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析 ACL 文件失败: %v", err)
+	}
+
+	acl := &accessList{byToken: make(map[string]*aclPrincipal), byName: make(map[string]*aclPrincipal)}
+	for _, p := range file.Principals {
+		if p.Name == "" {
+			return nil, fmt.Errorf("ACL 中的调用方缺少 name")
+		}
+		if _, ok := acl.byName[p.Name]; ok {
+			return nil, fmt.Errorf("ACL 中的调用方 %s 重复", p.Name)
+		}
+		acl.byName[p.Name] = p
+		for _, token := range p.Tokens {
+			if token == "" {
+				return nil, fmt.Errorf("调用方 %s 的令牌为空", p.Name)
+			}
+			if other, ok := acl.byToken[token]; ok {
+				return nil, fmt.Errorf("调用方 %s 与 %s 使用了相同的令牌", p.Name, other.Name)
+			}
+			acl.byToken[token] = p
+		}
+	}
+	return acl, nil
+}
+
+// accessControl 校验调用方令牌和主题权限，ACL 文件可以在运行中重新加载
+type accessControl struct {
+	path    string
+	current atomic.Pointer[accessList]
+}
+
+// newAccessControl 加载 ACL 文件
+func newAccessControl(path string) (*accessControl, error) {
+	ac := &accessControl{path: path}
+	if err := ac.reload(); err != nil {
+		return nil, err
+	}
+	return ac, nil
+}
+
+// reload 重新读取 ACL 文件，文件无效时保留原有配置
+func (ac *accessControl) reload() error {
+	acl, err := loadACL(ac.path)
+	if err != nil {
+		return err
+	}
+	ac.current.Store(acl)
+	return nil
+}
+
+// principalKey 请求上下文中保存调用方名称的键
+type principalKey struct{}
+
+// internalKey 标记服务端内部发起的调用，如推送订阅的投递，这类调用不经过访问控制
+type internalKey struct{}
+
+// internalContext 返回标记为服务端内部调用的上下文
+func internalContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, internalKey{}, true)
+}
+
+// isInternal 判断是否为服务端内部调用
+func isInternal(ctx context.Context) bool {
+	return ctx.Value(internalKey{}) != nil
+}
+
+// principalName 返回请求的调用方名称
+func principalName(ctx context.Context) string {
+	name, _ := ctx.Value(principalKey{}).(string)
+	return name
+}
+
+// authenticate 根据元数据 authorization: Bearer <令牌> 识别调用方
+func (ac *accessControl) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "缺少访问令牌")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authorization 元数据的格式应为 Bearer <令牌>")
+	}
+	p, ok := ac.current.Load().byToken[token]
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "无效的访问令牌")
+	}
+	return context.WithValue(ctx, principalKey{}, p.Name), nil
+}
+
+// principal 返回请求的调用方在当前 ACL 中的配置，重新加载后被移除的调用方不再有任何权限
+func (ac *accessControl) principal(ctx context.Context) (*aclPrincipal, error) {
+	name := principalName(ctx)
+	if name == "" {
+		return nil, status.Error(codes.Unauthenticated, "未认证的请求")
+	}
+	p, ok := ac.current.Load().byName[name]
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "调用方 %s 已被移除", name)
+	}
+	return p, nil
+}
+
+// unaryInterceptor 认证一元调用
+func (ac *accessControl) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := ac.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamInterceptor 认证流式调用
+func (ac *accessControl) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := ac.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream 携带调用方信息的服务端流
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorize 校验调用方对主题的权限，未启用访问控制时总是允许
+func (s *pubSubServer) authorize(ctx context.Context, perm permission, topics ...string) error {
+	if s.acl == nil || isInternal(ctx) {
+		return nil
+	}
+	p, err := s.acl.principal(ctx)
+	if err != nil {
+		return err
+	}
+	for _, topic := range topics {
+		// 回复主题不在 ACL 中配置，只有能收到请求的调用方，即拥有请求主题订阅权限的调用方可以发布响应
+		if perm == permPublish && isReplyTopic(topic) {
+			requestTopic, ok := replyRequestTopic(topic)
+			if !ok || !p.allowed(permSubscribe, requestTopic) {
+				return status.Errorf(codes.PermissionDenied, "调用方 %s 不能向回复主题 %s 发布响应", p.Name, topic)
+			}
+			continue
+		}
+		if !p.allowed(perm, topic) {
+			return status.Errorf(codes.PermissionDenied, "调用方 %s 没有%s主题 %s 的权限", p.Name, perm, topic)
+		}
+	}
+	return nil
+}
+
+// permitted 判断调用方当前是否可以接收该主题的消息，用于模式订阅和 ACL 重新加载后的逐条检查
+func (s *pubSubServer) permitted(ctx context.Context, topic string) bool {
+	return s.authorize(ctx, permSubscribe, topic) == nil
+}
+
+// authorizeAdmin 校验调用方是否可以调用主题管理接口
+func (s *pubSubServer) authorizeAdmin(ctx context.Context) error {
+	if s.acl == nil || isInternal(ctx) {
+		return nil
+	}
+	p, err := s.acl.principal(ctx)
+	if err != nil {
+		return err
+	}
+	if !p.Admin {
+		return status.Errorf(codes.PermissionDenied, "调用方 %s 没有管理权限", p.Name)
+	}
+	return nil
+}
+
+// reloadOnHangup 收到 SIGHUP 时重新加载 ACL 文件
+func reloadOnHangup(ac *accessControl) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		if err := ac.reload(); err != nil {
+			log.Printf("重新加载 ACL 失败，继续使用原有配置: %v", err)
+			continue
+		}
+		log.Printf("已重新加载 ACL: %s", ac.path)
+	}
+}
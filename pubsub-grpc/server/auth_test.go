@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+const testACL = `{"principals": [
+  {"name": "alice", "tokens": ["alice-token"], "publish": ["orders"], "subscribe": ["orders*"]},
+  {"name": "bob", "tokens": ["bob-token"], "publish": ["orders"], "subscribe": ["orders*"]},
+  {"name": "billing", "tokens": ["billing-token"], "subscribe": ["orders"]},
+  {"name": "mallory", "tokens": ["mallory-token"], "subscribe": ["users"]}
+]}`
+
+// newTestACL 加载测试用的 ACL
+func newTestACL(t *testing.T) *accessControl {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "acl.json")
+	if err := os.WriteFile(path, []byte(testACL), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	acl, err := newAccessControl(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return acl
+}
+
+// callerContext 返回携带令牌并已通过认证的请求上下文
+func callerContext(t *testing.T, acl *accessControl, token string) context.Context {
+	t.Helper()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	ctx, err := acl.authenticate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
+
+// 只有创建订阅的调用方可以更新订阅
+func TestUpdateSubscriptionOwner(t *testing.T) {
+	acl := newTestACL(t)
+	s, _ := newTestServer(t, serverConfig{ACL: acl})
+	alice := callerContext(t, acl, "alice-token")
+	bob := callerContext(t, acl, "bob-token")
+
+	ctx, cancel := context.WithCancel(alice)
+	defer cancel()
+	discard := func(*pb.SubscribeResponse) error { return nil }
+	startSubscribe(ctx, s, &pb.SubscribeRequest{Topic: "orders", SubscriptionId: "alice-orders"}, discard)
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.subMu.Lock()
+		_, ok := s.subscriptions["alice-orders"]
+		s.subMu.Unlock()
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("订阅没有登记")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	req := &pb.UpdateSubscriptionRequest{SubscriptionId: "alice-orders", AddTopics: []string{"orders.eu"}}
+	if _, err := s.UpdateSubscription(bob, req); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("其他调用方更新订阅返回 %v，期望 PermissionDenied", err)
+	}
+	resp, err := s.UpdateSubscription(alice, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Topics) != 2 {
+		t.Errorf("更新后的主题为 %v，期望 orders 和 orders.eu", resp.Topics)
+	}
+}
+
+// 只有能收到请求的调用方可以向回复主题发布响应
+func TestAuthorizeReplyTopic(t *testing.T) {
+	acl := newTestACL(t)
+	s, _ := newTestServer(t, serverConfig{ACL: acl})
+	replyTopic := newReplyTopic("orders")
+
+	tests := []struct {
+		name  string
+		token string
+		topic string
+		code  codes.Code
+	}{
+		{"请求主题的订阅者", "billing-token", replyTopic, codes.OK},
+		{"无关的调用方", "mallory-token", replyTopic, codes.PermissionDenied},
+		{"没有请求主题的回复主题", "billing-token", "_reply.XYZ", codes.PermissionDenied},
+		{"其他请求主题的回复主题", "billing-token", newReplyTopic("users"), codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.authorize(callerContext(t, acl, tt.token), permPublish, tt.topic)
+			if code := status.Code(err); code != tt.code {
+				t.Errorf("发布到 %s 返回 %v，期望 %v", tt.topic, err, tt.code)
+			}
+		})
+	}
+}
+
+func TestReplyRequestTopic(t *testing.T) {
+	tests := []struct {
+		topic string
+		want  string
+		ok    bool
+	}{
+		{newReplyTopic("orders"), "orders", true},
+		{newReplyTopic("orders.eu"), "orders.eu", true},
+		{"_reply.ABC", "", false},
+		{"_reply..ABC", "", false},
+		{"_reply.orders.", "", false},
+		{"orders.ABC", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := replyRequestTopic(tt.topic)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("replyRequestTopic(%q) = %q, %v，期望 %q, %v", tt.topic, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// Broker 消息代理接口，负责在发布者和订阅者之间传递消息
+type Broker interface {
+	// Publish 发布消息到 env.Topic，返回消息 ID
+	Publish(ctx context.Context, env *pb.Message) (string, error)
+	// Subscribe 订阅主题，主题可以是 glob 模式
+	Subscribe(ctx context.Context, topics ...string) (Subscription, error)
+	// Close 关闭代理并释放资源
+	Close() error
+}
+
+// Inspector 支持查询主题信息的消息代理
+type Inspector interface {
+	// Topics 返回匹配模式的主题，包括有订阅者或保留了历史消息的主题
+	Topics(ctx context.Context, pattern string) ([]string, error)
+	// Subscribers 返回消息代理中主题的订阅数
+	Subscribers(ctx context.Context, topic string) (int64, error)
+}
+
+// Subscription 一个订阅，可以在订阅期间添加或移除主题
+type Subscription interface {
+	// Add 添加主题或模式
+	Add(ctx context.Context, topics ...string) error
+	// Remove 移除主题或模式
+	Remove(ctx context.Context, topics ...string) error
+	// Topics 返回当前订阅的全部主题和模式
+	Topics() []string
+	// Messages 返回消息通道，订阅关闭后通道关闭
+	Messages() <-chan *Delivery
+	// Close 关闭订阅
+	Close() error
+}
+
+// Aborter 可能被消息代理中途断开的订阅
+type Aborter interface {
+	// Err 返回订阅被断开的原因，消息通道关闭后调用，正常关闭时为 nil
+	Err() error
+}
+
+// subscriptionErr 返回订阅结束的原因，不支持断开的订阅返回 nil
+func subscriptionErr(sub Subscription) error {
+	if a, ok := sub.(Aborter); ok {
+		return a.Err()
+	}
+	return nil
+}
+
+// Buffered 直接把消息放入订阅者缓冲区的订阅，缓冲区按订阅者的溢出策略处理积压，
+// 订阅者从缓冲区读取消息，不再经过 Messages
+type Buffered interface {
+	Buffer() *subscriberBuffer
+}
+
+// subscriberBufferKey 订阅者缓冲区配置在上下文中的键
+type subscriberBufferKey struct{}
+
+// bufferOptions 订阅者缓冲区的大小和溢出策略
+type bufferOptions struct {
+	size   int
+	policy pb.OverflowPolicy
+}
+
+// withSubscriberBuffer 返回携带订阅者缓冲区配置的上下文，支持 Buffered 的消息代理据此创建订阅的缓冲区
+func withSubscriberBuffer(ctx context.Context, size int, policy pb.OverflowPolicy) context.Context {
+	return context.WithValue(ctx, subscriberBufferKey{}, bufferOptions{size: size, policy: policy})
+}
+
+// subscriberBufferOf 返回上下文中的订阅者缓冲区配置
+func subscriberBufferOf(ctx context.Context) (bufferOptions, bool) {
+	opts, ok := ctx.Value(subscriberBufferKey{}).(bufferOptions)
+	return opts, ok
+}
+
+// Delivery 投递给订阅者的一条消息
+type Delivery struct {
+	Message *pb.Message
+	Pattern string // 匹配该消息的订阅模式，精确订阅时为空
+}
+
+// newBroker 根据名称创建消息代理
+func newBroker(name, redisAddr string, retention Retention) (Broker, error) {
+	switch name {
+	case "redis":
+		return newRedisBroker(redisAddr, retention)
+	case "memory":
+		return newMemoryBroker(retention), nil
+	default:
+		return nil, fmt.Errorf("未知的消息代理: %s", name)
+	}
+}
+
+// isPattern 判断主题是否为 glob 模式
+func isPattern(topic string) bool {
+	return strings.ContainsAny(topic, "*?[")
+}
+
+// matchTopic 按 Redis PSUBSCRIBE 的 glob 规则匹配主题：
+// * 匹配任意字符串，? 匹配单个字符，[...] 匹配字符集合，\ 转义
+func matchTopic(pattern, topic string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(topic); i++ {
+				if matchTopic(pattern, topic[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(topic) == 0 {
+				return false
+			}
+			pattern, topic = pattern[1:], topic[1:]
+		case '[':
+			if len(topic) == 0 {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				// 没有闭合的 [ 按普通字符处理
+				if topic[0] != '[' {
+					return false
+				}
+				pattern, topic = pattern[1:], topic[1:]
+				continue
+			}
+			if !matchClass(pattern[1:end+1], topic[0]) {
+				return false
+			}
+			pattern, topic = pattern[end+2:], topic[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(topic) == 0 || pattern[0] != topic[0] {
+				return false
+			}
+			pattern, topic = pattern[1:], topic[1:]
+		}
+	}
+	return len(topic) == 0
+}
+
+// matchClass 匹配 [...] 中的字符集合，支持 ^ 取反和 a-z 范围
+func matchClass(class string, c byte) bool {
+	negate := len(class) > 0 && class[0] == '^'
+	if negate {
+		class = class[1:]
+	}
+	matched := false
+	for i := 0; i < len(class); i++ {
+		if i+2 < len(class) && class[i+1] == '-' {
+			lo, hi := class[i], class[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			i += 2
+			continue
+		}
+		if class[i] == c {
+			matched = true
+		}
+	}
+	return matched != negate
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// fanoutBacklog 未指定订阅者缓冲区时每个本地订阅者在分发点积压的消息上限，
+// 也是 OVERFLOW_BLOCK 订阅者缓冲区的下限
+const fanoutBacklog = 1000
+
+// fanoutBroker 在服务端复用上游订阅：每个主题或模式只向底层消息代理订阅一次，
+// 按引用计数管理本地订阅者，收到的消息分发给所有本地订阅者，最后一个订阅者离开时取消上游订阅
+type fanoutBroker struct {
+	inner Broker
+
+	mu        sync.Mutex
+	upstreams map[string]*fanoutUpstream // 按主题或模式索引的上游订阅
+	closed    bool
+}
+
+var (
+	_ Broker    = (*fanoutBroker)(nil)
+	_ Replayer  = (*fanoutBroker)(nil)
+	_ Inspector = (*fanoutBroker)(nil)
+	_ Aborter   = (*fanoutSubscription)(nil)
+	_ Buffered  = (*fanoutSubscription)(nil)
+)
+
+// newFanoutBroker 在消息代理之上创建服务端分发
+func newFanoutBroker(inner Broker) *fanoutBroker {
+	return &fanoutBroker{
+		inner:     inner,
+		upstreams: make(map[string]*fanoutUpstream),
+	}
+}
+
+// Publish 直接发布到底层消息代理
+func (b *fanoutBroker) Publish(ctx context.Context, env *pb.Message) (string, error) {
+	return b.inner.Publish(ctx, env)
+}
+
+// Replay 从底层消息代理回放历史消息
+func (b *fanoutBroker) Replay(ctx context.Context, topics []string, start startPosition) ([]*pb.Message, error) {
+	replayer, ok := b.inner.(Replayer)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "当前消息代理不支持回放历史消息")
+	}
+	return replayer.Replay(ctx, topics, start)
+}
+
+// Topics 返回底层消息代理中的主题
+func (b *fanoutBroker) Topics(ctx context.Context, pattern string) ([]string, error) {
+	inspector, ok := b.inner.(Inspector)
+	if !ok {
+		return nil, nil
+	}
+	return inspector.Topics(ctx, pattern)
+}
+
+// Subscribers 返回底层消息代理中主题的订阅数，共享的上游订阅只计一次
+func (b *fanoutBroker) Subscribers(ctx context.Context, topic string) (int64, error) {
+	inspector, ok := b.inner.(Inspector)
+	if !ok {
+		return 0, nil
+	}
+	return inspector.Subscribers(ctx, topic)
+}
+
+// Subscribe 创建本地订阅，共享已有的上游订阅。分发直接把消息放入订阅的缓冲区，
+// 上下文中有订阅者缓冲区配置时按该配置处理积压，否则积压超过 fanoutBacklog 条消息时断开。
+// 分发不会等待任何一个订阅者，OVERFLOW_BLOCK 的订阅者缓冲区满时同样被断开
+func (b *fanoutBroker) Subscribe(ctx context.Context, topics ...string) (Subscription, error) {
+	opts, ok := subscriberBufferOf(ctx)
+	if !ok {
+		opts = bufferOptions{size: fanoutBacklog, policy: pb.OverflowPolicy_OVERFLOW_DISCONNECT}
+	}
+	switch opts.policy {
+	case pb.OverflowPolicy_OVERFLOW_DROP_OLDEST, pb.OverflowPolicy_OVERFLOW_DROP_NEWEST, pb.OverflowPolicy_OVERFLOW_DISCONNECT:
+	default:
+		// 与 push 一样，未指定的策略按 OVERFLOW_BLOCK 处理
+		opts.size = max(opts.size, fanoutBacklog)
+	}
+	sub := &fanoutSubscription{
+		broker:   b,
+		topics:   make(map[string]bool),
+		buf:      newSubscriberBuffer(opts.size, opts.policy),
+		messages: make(chan *Delivery),
+		done:     make(chan struct{}),
+	}
+	if err := sub.Add(ctx, topics...); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Close 关闭全部上游订阅和底层消息代理。上游订阅仍保留在索引中，
+// 本地订阅关闭时照常离开；仍在建立中的上游订阅由建立者发现代理已关闭后关闭
+func (b *fanoutBroker) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	subs := make([]Subscription, 0, len(b.upstreams))
+	for _, up := range b.upstreams {
+		if up.sub != nil {
+			subs = append(subs, up.sub)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Close()
+	}
+	return b.inner.Close()
+}
+
+// join 将本地订阅加入主题的上游订阅，上游订阅不存在时创建。
+// 先在 b.mu 下放入占位的上游订阅，再在锁外使用调用方的上下文向底层消息代理订阅，
+// 同一主题的其他订阅者等待占位就绪，不影响其他主题的加入和离开
+func (b *fanoutBroker) join(ctx context.Context, topic string, sub *fanoutSubscription) error {
+	for {
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return errBrokerClosed
+		}
+		up, ok := b.upstreams[topic]
+		if !ok {
+			up = &fanoutUpstream{ready: make(chan struct{}), members: make(map[*fanoutSubscription]struct{})}
+			b.upstreams[topic] = up
+		}
+		up.refs++
+		b.mu.Unlock()
+
+		if !ok {
+			b.connect(ctx, topic, up)
+		}
+		select {
+		case <-up.ready:
+		case <-ctx.Done():
+			b.release(topic, up)
+			return ctx.Err()
+		}
+		if up.err != nil {
+			b.release(topic, up)
+			if !ok {
+				return up.err
+			}
+			// 建立者的订阅失败 (例如建立者已取消)，重新加入并自行建立上游订阅
+			continue
+		}
+		up.add(sub)
+		return nil
+	}
+}
+
+// connect 向底层消息代理订阅占位的上游订阅，完成后通知等待的订阅者。
+// 上下文只用于建立订阅，上游订阅在最后一个订阅者离开时关闭
+func (b *fanoutBroker) connect(ctx context.Context, topic string, up *fanoutUpstream) {
+	defer close(up.ready)
+
+	upSub, err := b.inner.Subscribe(ctx, topic)
+	b.mu.Lock()
+	if err == nil && b.closed {
+		upSub.Close()
+		err = errBrokerClosed
+	}
+	if err != nil {
+		up.err = err
+		if b.upstreams[topic] == up {
+			delete(b.upstreams, topic)
+		}
+		b.mu.Unlock()
+		return
+	}
+	up.sub = upSub
+	b.mu.Unlock()
+
+	go up.dispatch()
+	log.Printf("已建立主题 %s 的上游订阅", topic)
+}
+
+// leave 将本地订阅移出主题的上游订阅。
+// 移出订阅者可能要等待正在进行的分发，此时不持有 b.mu，避免阻塞其他主题
+func (b *fanoutBroker) leave(topic string, sub *fanoutSubscription) {
+	b.mu.Lock()
+	up, ok := b.upstreams[topic]
+	b.mu.Unlock()
+	if !ok {
+		return
+	}
+	up.remove(sub)
+	b.release(topic, up)
+}
+
+// release 释放上游订阅的一个引用，最后一个引用释放时关闭上游订阅
+func (b *fanoutBroker) release(topic string, up *fanoutUpstream) {
+	b.mu.Lock()
+	up.refs--
+	last := up.refs == 0
+	if last && b.upstreams[topic] == up {
+		delete(b.upstreams, topic)
+	}
+	upSub := up.sub
+	b.mu.Unlock()
+
+	if last && upSub != nil {
+		upSub.Close()
+		log.Printf("主题 %s 已没有订阅者，关闭上游订阅", topic)
+	}
+}
+
+// fanoutUpstream 一个主题或模式的上游订阅及其本地订阅者
+type fanoutUpstream struct {
+	ready chan struct{} // 上游订阅建立完成或失败后关闭
+	sub   Subscription  // 建立成功后设置，由 fanoutBroker.mu 保护
+	err   error         // 建立失败的原因，ready 关闭后才能读取
+	refs  int           // 本地订阅者和等待建立的订阅者数量，由 fanoutBroker.mu 保护
+
+	mu      sync.RWMutex
+	members map[*fanoutSubscription]struct{}
+}
+
+// add 添加本地订阅者
+func (up *fanoutUpstream) add(sub *fanoutSubscription) {
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	up.members[sub] = struct{}{}
+}
+
+// remove 移除本地订阅者
+func (up *fanoutUpstream) remove(sub *fanoutSubscription) {
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	delete(up.members, sub)
+}
+
+// dispatch 将上游消息分发给所有本地订阅者，直到上游订阅关闭。
+// 消息只读，所有订阅者共享同一个消息对象。消息不等待地放入订阅者的缓冲区，
+// 缓冲区满时按订阅者的溢出策略处理，需要断开的订阅者不影响其他订阅者和加入、离开
+func (up *fanoutUpstream) dispatch() {
+	for d := range up.sub.Messages() {
+		up.mu.RLock()
+		for sub := range up.members {
+			if err := sub.buf.offer(d); err != nil {
+				// 关闭订阅需要离开上游订阅，不能在持有读锁时进行
+				go sub.abort(err)
+			}
+		}
+		up.mu.RUnlock()
+	}
+}
+
+// fanoutSubscription 共享上游订阅的本地订阅
+type fanoutSubscription struct {
+	broker    *fanoutBroker
+	buf       *subscriberBuffer
+	messages  chan *Delivery // 没有读取缓冲区的调用方通过消息通道接收
+	forward   sync.Once
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error // 订阅被断开的原因，消息通道关闭后才能读取
+
+	mu     sync.Mutex
+	topics map[string]bool
+}
+
+// Add 添加主题或模式
+func (sub *fanoutSubscription) Add(ctx context.Context, topics ...string) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	for _, t := range topics {
+		if t == "" || sub.topics[t] {
+			continue
+		}
+		if err := sub.broker.join(ctx, t, sub); err != nil {
+			return err
+		}
+		sub.topics[t] = true
+	}
+	return nil
+}
+
+// Remove 移除主题或模式
+func (sub *fanoutSubscription) Remove(ctx context.Context, topics ...string) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	for _, t := range topics {
+		if !sub.topics[t] {
+			continue
+		}
+		sub.broker.leave(t, sub)
+		delete(sub.topics, t)
+	}
+	return nil
+}
+
+// Topics 返回当前订阅的全部主题和模式
+func (sub *fanoutSubscription) Topics() []string {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sortedKeys(sub.topics)
+}
+
+// Buffer 返回订阅的缓冲区
+func (sub *fanoutSubscription) Buffer() *subscriberBuffer {
+	return sub.buf
+}
+
+// Messages 返回消息通道，第一次调用时开始把缓冲区中的消息转发到消息通道
+func (sub *fanoutSubscription) Messages() <-chan *Delivery {
+	sub.forward.Do(func() { go sub.forwardMessages() })
+	return sub.messages
+}
+
+// forwardMessages 把缓冲区中的消息转发到消息通道，订阅结束后关闭消息通道
+func (sub *fanoutSubscription) forwardMessages() {
+	defer close(sub.messages)
+	for {
+		d, _, err := sub.buf.pop(context.Background())
+		if d == nil || err != nil {
+			return
+		}
+		select {
+		case sub.messages <- d:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// Err 返回订阅被断开的原因，正常关闭时为 nil
+func (sub *fanoutSubscription) Err() error {
+	return sub.err
+}
+
+// Close 关闭订阅
+func (sub *fanoutSubscription) Close() error {
+	sub.abort(nil)
+	return nil
+}
+
+// abort 关闭订阅并记录原因。离开全部上游订阅后结束缓冲区，
+// 缓冲区的读取方和消息通道的转发随之结束
+func (sub *fanoutSubscription) abort(err error) {
+	sub.closeOnce.Do(func() {
+		sub.err = err
+		close(sub.done)
+
+		sub.mu.Lock()
+		for t := range sub.topics {
+			sub.broker.leave(t, sub)
+		}
+		sub.topics = make(map[string]bool)
+		sub.mu.Unlock()
+
+		sub.buf.finish(err)
+	})
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// 共享上游订阅的订阅者各自收到一份消息，最后一个订阅者离开后取消上游订阅
+func TestFanoutBrokerShare(t *testing.T) {
+	ctx := context.Background()
+	inner := newMemoryBroker(Retention{})
+	b := newFanoutBroker(inner)
+	defer b.Close()
+
+	a, err := b.Subscribe(ctx, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := b.Subscribe(ctx, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := inner.Subscribers(ctx, "orders"); n != 1 {
+		t.Fatalf("上游订阅数为 %d，期望 1", n)
+	}
+
+	if _, err := b.Publish(ctx, testMessage("orders", "hello")); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, a)
+	receive(t, c)
+
+	a.Close()
+	c.Close()
+	if n, _ := inner.Subscribers(ctx, "orders"); n != 0 {
+		t.Fatalf("全部订阅者离开后上游订阅数为 %d，期望 0", n)
+	}
+}
+
+// 不读取消息的订阅者不拖慢同一主题的其他订阅者，缓冲区满后被断开
+func TestFanoutBrokerSlowSubscriber(t *testing.T) {
+	ctx := context.Background()
+	b := newFanoutBroker(newMemoryBroker(Retention{}))
+	defer b.Close()
+
+	slow, err := b.Subscribe(ctx, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, err := b.Subscribe(ctx, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total := fanoutBacklog + 10
+	received := make(chan int, 1)
+	go func() {
+		n := 0
+		for range fast.Messages() {
+			if n++; n == total {
+				break
+			}
+		}
+		received <- n
+	}()
+	for i := range total {
+		if _, err := b.Publish(ctx, testMessage("orders", fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case n := <-received:
+		if n != total {
+			t.Fatalf("快速订阅者收到 %d 条消息，期望 %d", n, total)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("慢速订阅者阻塞了同一主题的其他订阅者")
+	}
+
+	// 慢速订阅者的缓冲区满后被断开，消息通道关闭并报告断开原因
+	for range slow.Messages() {
+	}
+	if code := status.Code(subscriptionErr(slow)); code != codes.ResourceExhausted {
+		t.Errorf("慢速订阅者的断开原因为 %v，期望 ResourceExhausted", code)
+	}
+	if err := subscriptionErr(fast); err != nil {
+		t.Errorf("快速订阅者不应被断开: %v", err)
+	}
+}
+
+// 共享上游订阅的订阅者按各自的溢出策略处理积压
+func TestFanoutBrokerOverflowPolicy(t *testing.T) {
+	ctx := context.Background()
+	b := newFanoutBroker(newMemoryBroker(Retention{}))
+	defer b.Close()
+
+	oldest, err := b.Subscribe(withSubscriberBuffer(ctx, 2, pb.OverflowPolicy_OVERFLOW_DROP_OLDEST), "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer oldest.Close()
+	block, err := b.Subscribe(withSubscriberBuffer(ctx, 2, pb.OverflowPolicy_OVERFLOW_BLOCK), "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer block.Close()
+
+	for i := range 5 {
+		if _, err := b.Publish(ctx, testMessage("orders", fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitBuffered(t, block.(Buffered).Buffer(), 5)
+
+	buf := oldest.(Buffered).Buffer()
+	for _, want := range []string{"3", "4"} {
+		d, dropped, err := buf.pop(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(d.Message.Payload) != want || dropped != 3 {
+			t.Errorf("收到消息 %s (丢弃 %d 条)，期望最新的消息 %s (丢弃 3 条)", d.Message.Payload, dropped, want)
+		}
+	}
+	// OVERFLOW_BLOCK 的缓冲区不小于 fanoutBacklog，不会因为很小的缓冲区被断开
+	if err := subscriptionErr(block); err != nil {
+		t.Errorf("阻塞策略的订阅者被断开: %v", err)
+	}
+}
+
+// waitBuffered 等待缓冲区中有 n 条消息
+func waitBuffered(t *testing.T, buf *subscriberBuffer, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		buf.mu.Lock()
+		got := len(buf.items)
+		buf.mu.Unlock()
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("缓冲区中有 %d 条消息，期望 %d 条", got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// slowBroker 订阅指定主题时等待 release 关闭，或者上下文取消
+type slowBroker struct {
+	Broker
+	topic   string
+	release chan struct{}
+}
+
+func (b *slowBroker) Subscribe(ctx context.Context, topics ...string) (Subscription, error) {
+	if topics[0] == b.topic {
+		select {
+		case <-b.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return b.Broker.Subscribe(ctx, topics...)
+}
+
+// 建立上游订阅时不持有代理的锁：一个主题的上游订阅很慢时其他主题照常订阅，
+// 等待中的订阅者取消后释放引用，建立者取消后由等待的订阅者重新建立
+func TestFanoutBrokerJoinOutsideLock(t *testing.T) {
+	ctx := context.Background()
+	inner := &slowBroker{Broker: newMemoryBroker(Retention{}), topic: "slow", release: make(chan struct{})}
+	b := newFanoutBroker(inner)
+	defer b.Close()
+
+	creatorCtx, cancelCreator := context.WithCancel(ctx)
+	creator := make(chan error, 1)
+	go func() {
+		_, err := b.Subscribe(creatorCtx, "slow")
+		creator <- err
+	}()
+	waitFanoutMembers(t, b, "slow", 1)
+
+	subscribed := make(chan error, 1)
+	go func() {
+		sub, err := b.Subscribe(ctx, "orders")
+		if err == nil {
+			sub.Close()
+		}
+		subscribed <- err
+	}()
+	select {
+	case err := <-subscribed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("慢速的上游订阅阻塞了其他主题的订阅")
+	}
+
+	waiterCtx, cancelWaiter := context.WithCancel(ctx)
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := b.Subscribe(waiterCtx, "slow")
+		waiter <- err
+	}()
+	waitFanoutMembers(t, b, "slow", 2)
+	cancelWaiter()
+	if err := <-waiter; err != context.Canceled {
+		t.Fatalf("取消等待返回 %v，期望 context.Canceled", err)
+	}
+	waitFanoutMembers(t, b, "slow", 1)
+
+	// 建立者取消后，仍在等待的订阅者自行建立上游订阅
+	joined := make(chan Subscription, 1)
+	go func() {
+		sub, err := b.Subscribe(ctx, "slow")
+		if err != nil {
+			t.Error(err)
+		}
+		joined <- sub
+	}()
+	waitFanoutMembers(t, b, "slow", 2)
+	cancelCreator()
+	if err := <-creator; err != context.Canceled {
+		t.Fatalf("建立者取消后返回 %v，期望 context.Canceled", err)
+	}
+	close(inner.release)
+	sub := <-joined
+	if sub == nil {
+		return
+	}
+	defer sub.Close()
+	if _, err := b.Publish(ctx, testMessage("slow", "hello")); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, sub)
+}
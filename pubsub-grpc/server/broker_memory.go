@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// memoryBufferSize 内存订阅的消息通道容量
+const memoryBufferSize = 100
+
+// errBrokerClosed 代理已关闭
+var errBrokerClosed = errors.New("消息代理已关闭")
+
+// memoryBroker 进程内的消息代理，不依赖 Redis，适合本地运行和测试
+type memoryBroker struct {
+	mu     sync.RWMutex
+	subs   map[*memorySubscription]struct{}
+	closed bool
+
+	retention Retention
+	histMu    sync.Mutex
+	history   map[string][]*pb.Message // 每个主题保留的历史消息，按发布顺序排列
+}
+
+var (
+	_ Broker    = (*memoryBroker)(nil)
+	_ Replayer  = (*memoryBroker)(nil)
+	_ Inspector = (*memoryBroker)(nil)
+)
+
+// newMemoryBroker 创建内存消息代理
+func newMemoryBroker(retention Retention) *memoryBroker {
+	return &memoryBroker{
+		subs:      make(map[*memorySubscription]struct{}),
+		retention: retention,
+		history:   make(map[string][]*pb.Message),
+	}
+}
+
+// memoryDelivery 发送给一个订阅的消息
+type memoryDelivery struct {
+	sub *memorySubscription
+	d   *Delivery
+}
+
+// Publish 将消息投递给所有匹配的订阅，每个匹配的主题或模式投递一次。
+// 在锁内选出匹配的订阅，释放锁后再发送，慢速订阅者不会阻塞订阅和取消订阅
+func (b *memoryBroker) Publish(ctx context.Context, env *pb.Message) (string, error) {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return "", errBrokerClosed
+	}
+	b.retain(env)
+	var deliveries []memoryDelivery
+	for sub := range b.subs {
+		for _, t := range sub.Topics() {
+			d := &Delivery{Message: proto.Clone(env).(*pb.Message)}
+			if isPattern(t) {
+				if !matchTopic(t, env.Topic) {
+					continue
+				}
+				d.Pattern = t
+			} else if t != env.Topic {
+				continue
+			}
+			deliveries = append(deliveries, memoryDelivery{sub: sub, d: d})
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, md := range deliveries {
+		if err := md.sub.deliver(ctx, md.d); err != nil {
+			return "", err
+		}
+	}
+	return env.Id, nil
+}
+
+// retain 保存主题的历史消息，超出保留数量的旧消息被丢弃，请求的回复主题不保留历史
+func (b *memoryBroker) retain(env *pb.Message) {
+	if b.retention.MaxMessages <= 0 || isReplyTopic(env.Topic) {
+		return
+	}
+
+	b.histMu.Lock()
+	defer b.histMu.Unlock()
+
+	history := append(b.history[env.Topic], proto.Clone(env).(*pb.Message))
+	if len(history) > b.retention.MaxMessages {
+		history = append([]*pb.Message(nil), history[len(history)-b.retention.MaxMessages:]...)
+	}
+	b.history[env.Topic] = history
+}
+
+// Replay 返回匹配主题在起始位置之后的历史消息
+func (b *memoryBroker) Replay(ctx context.Context, topics []string, start startPosition) ([]*pb.Message, error) {
+	b.histMu.Lock()
+	var msgs []*pb.Message
+	for topic, history := range b.history {
+		if matchedPattern(topics, topic) == "" && !slices.Contains(topics, topic) {
+			continue
+		}
+		for _, m := range selectHistory(history, start, b.retention.MaxAge) {
+			msgs = append(msgs, proto.Clone(m).(*pb.Message))
+		}
+	}
+	b.histMu.Unlock()
+
+	sortMessages(msgs)
+	return trimLastN(msgs, start), nil
+}
+
+// Topics 返回有订阅者或保留了历史消息的主题
+func (b *memoryBroker) Topics(ctx context.Context, pattern string) ([]string, error) {
+	var topics []string
+	b.mu.RLock()
+	for sub := range b.subs {
+		for _, t := range sub.Topics() {
+			if !isPattern(t) && matchTopic(pattern, t) && !slices.Contains(topics, t) {
+				topics = append(topics, t)
+			}
+		}
+	}
+	b.mu.RUnlock()
+
+	b.histMu.Lock()
+	for t := range b.history {
+		if matchTopic(pattern, t) && !slices.Contains(topics, t) {
+			topics = append(topics, t)
+		}
+	}
+	b.histMu.Unlock()
+	return topics, nil
+}
+
+// Subscribers 返回精确订阅该主题的订阅数，与 PUBSUB NUMSUB 一致不包括模式订阅
+func (b *memoryBroker) Subscribers(ctx context.Context, topic string) (int64, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var n int64
+	for sub := range b.subs {
+		if slices.Contains(sub.Topics(), topic) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Subscribe 创建内存订阅
+func (b *memoryBroker) Subscribe(ctx context.Context, topics ...string) (Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, errBrokerClosed
+	}
+	sub := &memorySubscription{
+		broker:   b,
+		topics:   make(map[string]bool),
+		messages: make(chan *Delivery, memoryBufferSize),
+		done:     make(chan struct{}),
+	}
+	sub.Add(ctx, topics...)
+	b.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// Close 关闭代理及其全部订阅
+func (b *memoryBroker) Close() error {
+	b.mu.Lock()
+	subs := make([]*memorySubscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Close()
+	}
+	return nil
+}
+
+// memorySubscription 内存订阅
+type memorySubscription struct {
+	broker    *memoryBroker
+	messages  chan *Delivery
+	done      chan struct{}
+	closeOnce sync.Once
+
+	sendMu sync.RWMutex // 发送时持有读锁，关闭消息通道时持有写锁
+	closed bool
+
+	mu     sync.Mutex
+	topics map[string]bool
+}
+
+// deliver 向订阅发送消息，订阅已关闭时丢弃
+func (sub *memorySubscription) deliver(ctx context.Context, d *Delivery) error {
+	sub.sendMu.RLock()
+	defer sub.sendMu.RUnlock()
+	if sub.closed {
+		return nil
+	}
+	select {
+	case sub.messages <- d:
+	case <-sub.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Add 添加主题或模式
+func (sub *memorySubscription) Add(ctx context.Context, topics ...string) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	for _, t := range topics {
+		if t != "" {
+			sub.topics[t] = true
+		}
+	}
+	return nil
+}
+
+// Remove 移除主题或模式
+func (sub *memorySubscription) Remove(ctx context.Context, topics ...string) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	for _, t := range topics {
+		delete(sub.topics, t)
+	}
+	return nil
+}
+
+// Topics 返回当前订阅的全部主题和模式
+func (sub *memorySubscription) Topics() []string {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sortedKeys(sub.topics)
+}
+
+// Messages 返回消息通道
+func (sub *memorySubscription) Messages() <-chan *Delivery {
+	return sub.messages
+}
+
+// Close 关闭订阅并从代理中移除。先关闭 done 使阻塞中的发送返回，
+// 等待正在进行的发送结束后才能安全关闭消息通道
+func (sub *memorySubscription) Close() error {
+	sub.closeOnce.Do(func() {
+		close(sub.done)
+
+		sub.broker.mu.Lock()
+		delete(sub.broker.subs, sub)
+		sub.broker.mu.Unlock()
+
+		sub.sendMu.Lock()
+		sub.closed = true
+		close(sub.messages)
+		sub.sendMu.Unlock()
+	})
+	return nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// testMessage 构造一条发往 topic 的消息
+func testMessage(topic, payload string) *pb.Message {
+	return &pb.Message{Id: newMessageID(time.Now()), Topic: topic, Payload: []byte(payload)}
+}
+
+// receive 从订阅读取一条消息，超时则测试失败
+func receive(t *testing.T, sub Subscription) *Delivery {
+	t.Helper()
+	select {
+	case d, ok := <-sub.Messages():
+		if !ok {
+			t.Fatal("订阅的消息通道已关闭")
+		}
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("等待消息超时")
+	}
+	return nil
+}
+
+// expectNone 确认订阅在短时间内没有收到消息
+func expectNone(t *testing.T, sub Subscription) {
+	t.Helper()
+	select {
+	case d := <-sub.Messages():
+		t.Fatalf("不应收到消息: %v", d.Message)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMemoryBrokerPublishSubscribe(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBroker(Retention{})
+	defer b.Close()
+
+	sub, err := b.Subscribe(ctx, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := b.Subscribe(ctx, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.Publish(ctx, testMessage("orders", "hello")); err != nil {
+		t.Fatal(err)
+	}
+	d := receive(t, sub)
+	if string(d.Message.Payload) != "hello" || d.Pattern != "" {
+		t.Errorf("收到 %q (模式 %q)，期望 hello", d.Message.Payload, d.Pattern)
+	}
+	expectNone(t, other)
+}
+
+func TestMemoryBrokerPattern(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBroker(Retention{})
+	defer b.Close()
+
+	sub, err := b.Subscribe(ctx, "orders.*", "orders.eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Publish(ctx, testMessage("orders.eu", "x")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 精确主题和模式各投递一次
+	patterns := map[string]bool{}
+	for range 2 {
+		patterns[receive(t, sub).Pattern] = true
+	}
+	if !patterns[""] || !patterns["orders.*"] {
+		t.Errorf("收到的模式 %v，期望精确主题和 orders.*", patterns)
+	}
+
+	if err := sub.Remove(ctx, "orders.*", "orders.eu"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Publish(ctx, testMessage("orders.eu", "y")); err != nil {
+		t.Fatal(err)
+	}
+	expectNone(t, sub)
+}
+
+func TestMemoryBrokerReplay(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBroker(Retention{MaxMessages: 3})
+	defer b.Close()
+
+	var ids []string
+	for i := range 5 {
+		id, err := b.Publish(ctx, testMessage("logs", fmt.Sprint(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+		time.Sleep(time.Millisecond)
+	}
+
+	msgs, err := b.Replay(ctx, []string{"logs"}, startPosition{kind: pb.StartPosition_START_EARLIEST})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 3 || string(msgs[0].Payload) != "2" || string(msgs[2].Payload) != "4" {
+		t.Fatalf("回放 %d 条消息，期望保留最近 3 条", len(msgs))
+	}
+
+	msgs, err = b.Replay(ctx, []string{"log*"}, startPosition{kind: pb.StartPosition_START_AFTER_ID, afterID: ids[3]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].Id != ids[4] {
+		t.Fatalf("从 %s 之后回放得到 %d 条消息，期望 1 条", ids[3], len(msgs))
+	}
+}
+
+// 慢速订阅者只阻塞发往其主题的发布，不影响订阅、取消订阅和其他主题的发布
+func TestMemoryBrokerSlowSubscriber(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBroker(Retention{})
+	defer b.Close()
+
+	slow, err := b.Subscribe(ctx, "slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range memoryBufferSize {
+		if _, err := b.Publish(ctx, testMessage("slow", fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := b.Publish(ctx, testMessage("slow", "blocked"))
+		blocked <- err
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fast, err := b.Subscribe(ctx, "fast")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err := b.Publish(ctx, testMessage("fast", "ok")); err != nil {
+			t.Error(err)
+		}
+		receive(t, fast)
+		fast.Close()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("慢速订阅者阻塞了其他主题的订阅和发布")
+	}
+
+	// 关闭慢速订阅后，阻塞中的发布返回
+	slow.Close()
+	select {
+	case err := <-blocked:
+		if err != nil {
+			t.Errorf("发布失败: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("关闭订阅后发布仍然阻塞")
+	}
+}
+
+func TestMemoryBrokerClose(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBroker(Retention{})
+
+	sub, err := b.Subscribe(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+	if _, ok := <-sub.Messages(); ok {
+		t.Error("代理关闭后订阅的消息通道应关闭")
+	}
+	if _, err := b.Publish(ctx, testMessage("a", "x")); err != errBrokerClosed {
+		t.Errorf("关闭后发布返回 %v，期望 errBrokerClosed", err)
+	}
+	if _, err := b.Subscribe(ctx, "a"); err != errBrokerClosed {
+		t.Errorf("关闭后订阅返回 %v，期望 errBrokerClosed", err)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// historyKeyPrefix 保存主题历史消息的 Redis Stream 键前缀
+const historyKeyPrefix = "pubsub:history:"
+
+// redisBroker 基于 Redis PUBLISH/SUBSCRIBE 的消息代理，
+// 历史消息保存在每个主题对应的 Redis Stream 中
+type redisBroker struct {
+	client    *redis.Client
+	retention Retention
+}
+
+var (
+	_ Broker    = (*redisBroker)(nil)
+	_ Replayer  = (*redisBroker)(nil)
+	_ Inspector = (*redisBroker)(nil)
+)
+
+// newRedisBroker 连接 Redis 并创建消息代理
+func newRedisBroker(addr string, retention Retention) (*redisBroker, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: addr,
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("连接 Redis %s 失败: %w", addr, err)
+	}
+	return &redisBroker{client: client, retention: retention}, nil
+}
+
+// Publish 将消息信封编码后通过 PUBLISH 发布，开启历史保留时先写入历史 Stream 并使用 Stream ID 作为消息 ID
+func (b *redisBroker) Publish(ctx context.Context, env *pb.Message) (string, error) {
+	if err := b.retain(ctx, env); err != nil {
+		return "", err
+	}
+	data, err := encodeEnvelope(env)
+	if err != nil {
+		return "", err
+	}
+	if err := b.client.Publish(ctx, env.Topic, data).Err(); err != nil {
+		return "", err
+	}
+	return env.Id, nil
+}
+
+// retain 将消息追加到主题的历史 Stream，请求的回复主题不保留历史
+func (b *redisBroker) retain(ctx context.Context, env *pb.Message) error {
+	if b.retention.MaxMessages <= 0 || isReplyTopic(env.Topic) {
+		return nil
+	}
+
+	data, err := encodeEnvelope(env)
+	if err != nil {
+		return err
+	}
+	key := historyKeyPrefix + env.Topic
+	id, err := b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: int64(b.retention.MaxMessages),
+		Approx: true,
+		Values: map[string]any{streamField: data},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("保存历史消息失败: %w", err)
+	}
+	env.Id = id
+
+	if b.retention.MaxAge > 0 {
+		b.client.Expire(ctx, key, b.retention.MaxAge)
+	}
+	return nil
+}
+
+// Replay 从主题的历史 Stream 中读取起始位置之后的消息，模式通过 SCAN 匹配历史键
+func (b *redisBroker) Replay(ctx context.Context, topics []string, start startPosition) ([]*pb.Message, error) {
+	if start.latest() {
+		return nil, nil
+	}
+
+	keys := make(map[string]bool)
+	for _, t := range topics {
+		if !isPattern(t) {
+			keys[historyKeyPrefix+t] = true
+			continue
+		}
+		iter := b.client.Scan(ctx, 0, historyKeyPrefix+t, 100).Iterator()
+		for iter.Next(ctx) {
+			keys[iter.Val()] = true
+		}
+		if err := iter.Err(); err != nil {
+			return nil, fmt.Errorf("查找历史主题失败: %w", err)
+		}
+	}
+
+	var msgs []*pb.Message
+	for key := range keys {
+		entries, err := b.readHistory(ctx, key, start)
+		if err != nil {
+			return nil, fmt.Errorf("读取历史消息失败: %w", err)
+		}
+		topic := strings.TrimPrefix(key, historyKeyPrefix)
+		history := make([]*pb.Message, 0, len(entries))
+		for _, e := range entries {
+			history = append(history, streamEnvelope(topic, e))
+		}
+		msgs = append(msgs, selectHistory(history, start, b.retention.MaxAge)...)
+	}
+
+	sortMessages(msgs)
+	return trimLastN(msgs, start), nil
+}
+
+// readHistory 按起始位置读取历史 Stream 中的条目
+func (b *redisBroker) readHistory(ctx context.Context, key string, start startPosition) ([]redis.XMessage, error) {
+	switch start.kind {
+	case pb.StartPosition_START_AFTER_ID:
+		return b.client.XRange(ctx, key, "("+start.afterID, "+").Result()
+	case pb.StartPosition_START_FROM_TIME:
+		return b.client.XRange(ctx, key, strconv.FormatInt(start.since.UnixMilli(), 10), "+").Result()
+	case pb.StartPosition_START_LAST_N:
+		entries, err := b.client.XRevRangeN(ctx, key, "+", "-", int64(start.lastN)).Result()
+		slices.Reverse(entries)
+		return entries, err
+	default:
+		return b.client.XRange(ctx, key, "-", "+").Result()
+	}
+}
+
+// Topics 返回有订阅者或保留了历史消息的主题
+func (b *redisBroker) Topics(ctx context.Context, pattern string) ([]string, error) {
+	topics, err := b.client.PubSubChannels(ctx, pattern).Result()
+	if err != nil {
+		return nil, err
+	}
+	iter := b.client.Scan(ctx, 0, historyKeyPrefix+pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		topics = append(topics, strings.TrimPrefix(iter.Val(), historyKeyPrefix))
+	}
+	return topics, iter.Err()
+}
+
+// Subscribers 返回 PUBSUB NUMSUB 中主题的订阅数，不包括模式订阅
+func (b *redisBroker) Subscribers(ctx context.Context, topic string) (int64, error) {
+	counts, err := b.client.PubSubNumSub(ctx, topic).Result()
+	if err != nil {
+		return 0, err
+	}
+	return counts[topic], nil
+}
+
+// Subscribe 订阅主题，模式使用 PSUBSCRIBE
+func (b *redisBroker) Subscribe(ctx context.Context, topics ...string) (Subscription, error) {
+	sub := &redisSubscription{
+		pubsub:   b.client.Subscribe(ctx),
+		topics:   make(map[string]bool),
+		messages: make(chan *Delivery),
+		done:     make(chan struct{}),
+	}
+	if err := sub.Add(ctx, topics...); err != nil {
+		sub.pubsub.Close()
+		return nil, err
+	}
+	go sub.forward()
+	return sub, nil
+}
+
+// Close 关闭 Redis 连接
+func (b *redisBroker) Close() error {
+	return b.client.Close()
+}
+
+// redisSubscription 基于 redis.PubSub 的订阅
+type redisSubscription struct {
+	pubsub    *redis.PubSub
+	messages  chan *Delivery
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu     sync.Mutex
+	topics map[string]bool
+}
+
+// forward 将 Redis 消息解码为消息信封后转发
+func (sub *redisSubscription) forward() {
+	defer close(sub.messages)
+	for msg := range sub.pubsub.Channel() {
+		d := &Delivery{
+			Message: decodeEnvelope(msg.Channel, msg.Payload),
+			Pattern: msg.Pattern,
+		}
+		select {
+		case sub.messages <- d:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// Add 订阅主题，模式使用 PSUBSCRIBE
+func (sub *redisSubscription) Add(ctx context.Context, topics ...string) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	for _, t := range topics {
+		if t == "" || sub.topics[t] {
+			continue
+		}
+		var err error
+		if isPattern(t) {
+			err = sub.pubsub.PSubscribe(ctx, t)
+		} else {
+			err = sub.pubsub.Subscribe(ctx, t)
+		}
+		if err != nil {
+			return fmt.Errorf("订阅 %s 失败: %w", t, err)
+		}
+		sub.topics[t] = true
+	}
+	return nil
+}
+
+// Remove 取消订阅主题
+func (sub *redisSubscription) Remove(ctx context.Context, topics ...string) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	for _, t := range topics {
+		if !sub.topics[t] {
+			continue
+		}
+		var err error
+		if isPattern(t) {
+			err = sub.pubsub.PUnsubscribe(ctx, t)
+		} else {
+			err = sub.pubsub.Unsubscribe(ctx, t)
+		}
+		if err != nil {
+			return fmt.Errorf("取消订阅 %s 失败: %w", t, err)
+		}
+		delete(sub.topics, t)
+	}
+	return nil
+}
+
+// Topics 返回当前订阅的全部主题和模式
+func (sub *redisSubscription) Topics() []string {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sortedKeys(sub.topics)
+}
+
+// Messages 返回消息通道
+func (sub *redisSubscription) Messages() <-chan *Delivery {
+	return sub.messages
+}
+
+// Close 关闭订阅
+func (sub *redisSubscription) Close() error {
+	var err error
+	sub.closeOnce.Do(func() {
+		close(sub.done)
+		err = sub.pubsub.Close()
+	})
+	return err
+}
+
+// sortedKeys 返回排序后的集合元素
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// parseOverflowPolicy 解析命令行中的缓冲区溢出策略
+func parseOverflowPolicy(name string) (pb.OverflowPolicy, error) {
+	switch strings.ToLower(name) {
+	case "block":
+		return pb.OverflowPolicy_OVERFLOW_BLOCK, nil
+	case "drop-oldest":
+		return pb.OverflowPolicy_OVERFLOW_DROP_OLDEST, nil
+	case "drop-newest":
+		return pb.OverflowPolicy_OVERFLOW_DROP_NEWEST, nil
+	case "disconnect":
+		return pb.OverflowPolicy_OVERFLOW_DISCONNECT, nil
+	default:
+		return 0, fmt.Errorf("未知的溢出策略: %s", name)
+	}
+}
+
+// subscriberBuffer 订阅者的有界消息缓冲区，缓冲区满时按溢出策略处理新消息
+type subscriberBuffer struct {
+	size   int
+	policy pb.OverflowPolicy
+
+	mu      sync.Mutex
+	items   []*Delivery
+	dropped uint64
+	done    bool  // 不会再有新消息
+	err     error // 订阅因溢出被断开
+
+	notify chan struct{} // 有新消息或状态变化
+	space  chan struct{} // 缓冲区有空位
+}
+
+// newSubscriberBuffer 创建订阅者缓冲区
+func newSubscriberBuffer(size int, policy pb.OverflowPolicy) *subscriberBuffer {
+	return &subscriberBuffer{
+		size:   size,
+		policy: policy,
+		notify: make(chan struct{}, 1),
+		space:  make(chan struct{}, 1),
+	}
+}
+
+// notifyOne 非阻塞地发出通知
+func notifyOne(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// fill 从订阅中读取消息放入缓冲区，直到订阅关闭、上下文取消或因溢出断开。
+// 订阅被消息代理断开时，订阅者收到断开的原因
+func (b *subscriberBuffer) fill(ctx context.Context, sub Subscription) {
+	for d := range sub.Messages() {
+		if err := b.push(ctx, d); err != nil {
+			b.finish(err)
+			return
+		}
+	}
+	b.finish(subscriptionErr(sub))
+}
+
+// push 放入一条消息，缓冲区满时按溢出策略处理
+func (b *subscriberBuffer) push(ctx context.Context, d *Delivery) error {
+	for {
+		ok, err := b.tryPush(d)
+		if ok || err != nil {
+			return err
+		}
+
+		// OVERFLOW_BLOCK：等待订阅者取走消息
+		select {
+		case <-b.space:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// offer 不等待地放入一条消息，供不能阻塞的分发使用。缓冲区满时丢弃策略照常处理，
+// OVERFLOW_BLOCK 无法等待订阅者，与 OVERFLOW_DISCONNECT 一样返回 RESOURCE_EXHAUSTED
+func (b *subscriberBuffer) offer(d *Delivery) error {
+	ok, err := b.tryPush(d)
+	if !ok && err == nil {
+		return status.Errorf(codes.ResourceExhausted, "订阅者处理过慢，缓冲区已满 (%d 条消息)，共享上游订阅时不能阻塞分发", b.size)
+	}
+	return err
+}
+
+// tryPush 放入一条消息，缓冲区满且溢出策略为 OVERFLOW_BLOCK 时返回 false。订阅结束后的消息直接丢弃
+func (b *subscriberBuffer) tryPush(d *Delivery) (bool, error) {
+	b.mu.Lock()
+	if b.done {
+		b.mu.Unlock()
+		return true, nil
+	}
+	if len(b.items) < b.size {
+		b.items = append(b.items, d)
+		b.mu.Unlock()
+		notifyOne(b.notify)
+		return true, nil
+	}
+
+	switch b.policy {
+	case pb.OverflowPolicy_OVERFLOW_DROP_OLDEST:
+		b.items = append(b.items[1:], d)
+		b.dropped++
+		b.mu.Unlock()
+		notifyOne(b.notify)
+		return true, nil
+	case pb.OverflowPolicy_OVERFLOW_DROP_NEWEST:
+		b.dropped++
+		b.mu.Unlock()
+		return true, nil
+	case pb.OverflowPolicy_OVERFLOW_DISCONNECT:
+		b.mu.Unlock()
+		return false, status.Errorf(codes.ResourceExhausted, "订阅者处理过慢，缓冲区已满 (%d 条消息)", b.size)
+	}
+	b.mu.Unlock()
+	return false, nil
+}
+
+// finish 标记不会再有新消息，err 不为空时订阅者将收到该错误
+func (b *subscriberBuffer) finish(err error) {
+	b.mu.Lock()
+	if !b.done {
+		b.done = true
+		b.err = err
+	}
+	b.mu.Unlock()
+	notifyOne(b.notify)
+}
+
+// pop 取出一条消息及当前累计丢弃数；缓冲区为空且订阅结束时返回 nil 消息
+func (b *subscriberBuffer) pop(ctx context.Context) (*Delivery, uint64, error) {
+	for {
+		b.mu.Lock()
+		if b.err != nil {
+			err := b.err
+			b.mu.Unlock()
+			return nil, 0, err
+		}
+		if len(b.items) > 0 {
+			d := b.items[0]
+			b.items[0] = nil
+			b.items = b.items[1:]
+			dropped := b.dropped
+			b.mu.Unlock()
+			notifyOne(b.space)
+			return d, dropped, nil
+		}
+		if b.done {
+			b.mu.Unlock()
+			return nil, 0, nil
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-b.notify:
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		}
+	}
+}
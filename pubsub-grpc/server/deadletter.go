@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "pubsub/proto/pubsub"
+)
+
+const (
+	errorsKeyPrefix = "pubsub:errors:" // 记录消费者组中消息最后一次处理失败原因的 Hash 键前缀
+	nackIdle        = 24 * time.Hour   // 否认的消息被标记为已空闲的时间，使其立即可以被重新认领
+)
+
+// 死信消息的消息头
+const (
+	headerOriginalTopic    = "x-original-topic"        // 原主题
+	headerOriginalID       = "x-original-id"           // 原消息 ID
+	headerDeliveryAttempts = "x-delivery-attempts"     // 移入死信主题前的投递次数
+	headerLastError        = "x-last-error"            // 最后一次处理失败的原因
+	headerDeadLetterGroup  = "x-dead-letter-group"     // 放弃处理该消息的消费者组
+	headerDeadLetterTime   = "x-dead-letter-time"      // 移入死信主题的时间
+	headerOriginalTime     = "x-original-publish-time" // 原消息的发布时间
+)
+
+// errorsKey 返回消费者组记录处理失败原因的 Hash 键
+func errorsKey(topic, group string) string {
+	return errorsKeyPrefix + topic + ":" + group
+}
+
+// deadLetterPolicy 返回订阅的最大投递次数和死信主题，请求未指定时使用服务端默认值
+func (s *pubSubServer) deadLetterPolicy(req *pb.SubscribeRequest) (int64, string) {
+	maxAttempts := int64(s.maxDeliveryAttempts)
+	if req.MaxDeliveryAttempts > 0 {
+		maxAttempts = int64(req.MaxDeliveryAttempts)
+	}
+	topic := req.DeadLetterTopic
+	if topic == "" {
+		topic = req.Topic + s.deadLetterSuffix
+	}
+	return maxAttempts, topic
+}
+
+// pendingEntry 返回消费者组中待确认消息的持有者和投递次数，消息不在待确认列表中时返回 false
+func (s *pubSubServer) pendingEntry(ctx context.Context, stream, group, id string) (redis.XPendingExt, bool, error) {
+	pending, err := s.redisClient.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  group,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return redis.XPendingExt{}, false, err
+	}
+	return pending[0], true, nil
+}
+
+// findPending 在消费者组对应的 Redis 消费者组中查找待确认的消息，启用分区时消息只在所属分区 Stream 的组中待确认
+func (s *pubSubServer) findPending(ctx context.Context, topic, group, id string) (consumerGroup, redis.XPendingExt, bool, error) {
+	for _, g := range s.consumerGroups(topic, group) {
+		entry, ok, err := s.pendingEntry(ctx, g.stream, g.name, id)
+		if err != nil || ok {
+			return g, entry, ok, err
+		}
+	}
+	return consumerGroup{}, redis.XPendingExt{}, false, nil
+}
+
+// deadLetter 将消息移入死信主题并在原消费者组中确认，消息头记录原主题、投递次数和最后一次失败原因
+func (s *pubSubServer) deadLetter(ctx context.Context, c *groupConsumer, g consumerGroup, msg redis.XMessage, attempts int64) error {
+	topic, group, deadLetterTopic := c.req.Topic, c.req.Group, c.deadLetterTopic
+	lastError, err := s.redisClient.HGet(ctx, errorsKey(topic, group), msg.ID).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
+	env := streamEnvelope(topic, msg)
+	dead := proto.Clone(env).(*pb.Message)
+	dead.Topic = deadLetterTopic
+	dead.PublishTime = timestamppb.Now()
+	if dead.Headers == nil {
+		dead.Headers = make(map[string]string)
+	}
+	dead.Headers[headerOriginalTopic] = topic
+	dead.Headers[headerOriginalID] = msg.ID
+	dead.Headers[headerOriginalTime] = env.PublishTime.AsTime().Format(time.RFC3339Nano)
+	dead.Headers[headerDeliveryAttempts] = strconv.FormatInt(attempts, 10)
+	dead.Headers[headerLastError] = lastError
+	dead.Headers[headerDeadLetterGroup] = group
+	dead.Headers[headerDeadLetterTime] = dead.PublishTime.AsTime().Format(time.RFC3339Nano)
+
+	id, err := s.publishDurable(ctx, dead)
+	if err != nil {
+		return err
+	}
+	s.stats.record(deadLetterTopic, dead.PublishTime.AsTime())
+	if err := s.redisClient.XAck(ctx, g.stream, g.name, msg.ID).Err(); err != nil {
+		return err
+	}
+	s.redisClient.HDel(ctx, errorsKey(topic, group), msg.ID)
+
+	log.Printf("消息 %s (主题 %s, 消费者组 %s) 已投递 %d 次仍未处理成功，移入死信主题 %s (消息 %s)",
+		msg.ID, topic, group, attempts, deadLetterTopic, id)
+	return nil
+}
+
+// Nack 报告消费者组中的消息处理失败，记录失败原因并使消息立即可以被重新投递
+func (s *pubSubServer) Nack(ctx context.Context, req *pb.NackRequest) (*pb.NackResponse, error) {
+	if !s.durable {
+		return nil, status.Error(codes.FailedPrecondition, "消息否认仅在持久化模式下可用")
+	}
+	if req.Topic == "" || req.Group == "" {
+		return nil, status.Error(codes.InvalidArgument, "必须指定主题和消费者组")
+	}
+	if err := s.authorize(ctx, permSubscribe, req.Topic); err != nil {
+		return nil, err
+	}
+
+	var nacked int32
+	for _, id := range req.Ids {
+		g, entry, ok, err := s.findPending(ctx, req.Topic, req.Group, id)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "查询待确认消息失败: %v", err)
+		}
+		if !ok {
+			continue
+		}
+		if req.Error != "" {
+			if err := s.redisClient.HSet(ctx, errorsKey(req.Topic, req.Group), id, req.Error).Err(); err != nil {
+				return nil, status.Errorf(codes.Internal, "记录失败原因失败: %v", err)
+			}
+		}
+
+		// 将消息标记为长时间未确认，下一次 XAUTOCLAIM 即会重新投递；保持投递次数不变
+		err = s.redisClient.Do(ctx, "XCLAIM", g.stream, g.name, entry.Consumer, 0, id,
+			"IDLE", nackIdle.Milliseconds(), "RETRYCOUNT", entry.RetryCount, "JUSTID").Err()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "否认消息失败: %v", err)
+		}
+		nacked++
+	}
+	return &pb.NackResponse{NackedCount: nacked}, nil
+}
+
+// Redrive 将死信主题中的消息发布回原主题，并从死信主题中删除
+func (s *pubSubServer) Redrive(ctx context.Context, req *pb.RedriveRequest) (*pb.RedriveResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if !s.durable {
+		return nil, status.Error(codes.FailedPrecondition, "死信仅在持久化模式下可用")
+	}
+	if req.DeadLetterTopic == "" {
+		return nil, status.Error(codes.InvalidArgument, "必须指定死信主题")
+	}
+
+	entries, err := s.deadLetters(ctx, req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "读取死信失败: %v", err)
+	}
+
+	var redriven int32
+	for _, entry := range entries {
+		dead := streamEnvelope(req.DeadLetterTopic, entry)
+		original := dead.Headers[headerOriginalTopic]
+		if original == "" {
+			log.Printf("死信 %s 缺少原主题，跳过", entry.ID)
+			continue
+		}
+
+		env := proto.Clone(dead).(*pb.Message)
+		env.Topic = original
+		env.PublishTime = timestamppb.Now()
+		for k := range env.Headers {
+			if isDeadLetterHeader(k) {
+				delete(env.Headers, k)
+			}
+		}
+		id, err := s.publishDurable(ctx, env)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "重新发布消息失败: %v", err)
+		}
+		s.stats.record(original, env.PublishTime.AsTime())
+		if err := s.redisClient.XDel(ctx, req.DeadLetterTopic, entry.ID).Err(); err != nil {
+			return nil, status.Errorf(codes.Internal, "删除死信失败: %v", err)
+		}
+		log.Printf("死信 %s 已重新发布到主题 %s (消息 %s)", entry.ID, original, id)
+		redriven++
+	}
+	return &pb.RedriveResponse{RedrivenCount: redriven}, nil
+}
+
+// deadLetters 读取要重新投递的死信，指定 ID 时只读取这些消息
+func (s *pubSubServer) deadLetters(ctx context.Context, req *pb.RedriveRequest) ([]redis.XMessage, error) {
+	if len(req.Ids) > 0 {
+		var entries []redis.XMessage
+		for _, id := range req.Ids {
+			found, err := s.redisClient.XRange(ctx, req.DeadLetterTopic, id, id).Result()
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, found...)
+		}
+		return entries, nil
+	}
+
+	count := int64(req.MaxMessages)
+	if count <= 0 {
+		return s.redisClient.XRange(ctx, req.DeadLetterTopic, "-", "+").Result()
+	}
+	return s.redisClient.XRangeN(ctx, req.DeadLetterTopic, "-", "+", count).Result()
+}
+
+// isDeadLetterHeader 判断是否为移入死信主题时添加的消息头
+func isDeadLetterHeader(key string) bool {
+	switch key {
+	case headerOriginalTopic, headerOriginalID, headerOriginalTime, headerDeliveryAttempts,
+		headerLastError, headerDeadLetterGroup, headerDeadLetterTime:
+		return true
+	}
+	return false
+}
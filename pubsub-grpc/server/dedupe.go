@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+const (
+	dedupeKeyPrefix = "pubsub:dedupe:" // 幂等发布记录的键前缀，值为首次发布的消息 ID
+	dedupeSweep     = time.Minute      // 内存中清理过期幂等记录的间隔
+	// dedupeReserveTTL 发布中的幂等键的有效期。服务器在发布完成前退出时，
+	// 重试最迟在此之后可以再次发布，而不是在整个去重窗口内都被当作重复
+	dedupeReserveTTL = 30 * time.Second
+)
+
+// dedupeStore 记录去重窗口内已发布的幂等键
+type dedupeStore interface {
+	// Reserve 占用幂等键 dedupeReserveTTL，键已被占用时返回首次发布的消息 ID（仍在发布中时为空）和 true
+	Reserve(ctx context.Context, key string) (string, bool, error)
+	// Commit 记录幂等键对应的消息 ID，记录保留 window
+	Commit(ctx context.Context, key, id string, window time.Duration) error
+	// Release 发布失败时释放幂等键，使重试可以再次发布
+	Release(ctx context.Context, key string) error
+}
+
+// newDedupeStore 根据消息代理选择幂等记录的存储，Redis 消息代理使用 Redis，多个服务器副本共享去重状态
+func newDedupeStore(broker Broker) dedupeStore {
+	if rb, ok := broker.(*redisBroker); ok {
+		return &redisDedupeStore{client: rb.client}
+	}
+	return &memoryDedupeStore{entries: make(map[string]dedupeEntry)}
+}
+
+// dedupeKey 返回发布请求的幂等键，请求未指定生产者序列号或幂等键时返回空字符串。
+// 幂等键在主题内唯一，不同主题使用相同的键互不影响
+func dedupeKey(req *pb.PublishRequest) (string, error) {
+	switch {
+	case req.IdempotencyKey != "" && req.ProducerId != "":
+		return "", status.Error(codes.InvalidArgument, "idempotency_key 和 producer_id 只能指定一个")
+	case req.IdempotencyKey != "":
+		return req.Topic + ":key:" + req.IdempotencyKey, nil
+	case req.ProducerId != "":
+		if req.ProducerSequence <= 0 {
+			return "", status.Error(codes.InvalidArgument, "指定 producer_id 时 producer_sequence 必须为正数")
+		}
+		return req.Topic + ":producer:" + req.ProducerId + ":" + strconv.FormatInt(req.ProducerSequence, 10), nil
+	case req.ProducerSequence != 0:
+		return "", status.Error(codes.InvalidArgument, "指定 producer_sequence 时必须指定 producer_id")
+	}
+	return "", nil
+}
+
+// redisDedupeStore 使用带过期时间的 Redis 键保存幂等记录
+type redisDedupeStore struct {
+	client *redis.Client
+}
+
+// Reserve 以 SET NX 占用幂等键
+func (st *redisDedupeStore) Reserve(ctx context.Context, key string) (string, bool, error) {
+	ok, err := st.client.SetNX(ctx, dedupeKeyPrefix+key, "", dedupeReserveTTL).Result()
+	if err != nil || ok {
+		return "", false, err
+	}
+	id, err := st.client.Get(ctx, dedupeKeyPrefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		err = nil
+	}
+	return id, true, err
+}
+
+// Commit 写入消息 ID，去重窗口从发布成功时开始计算
+func (st *redisDedupeStore) Commit(ctx context.Context, key, id string, window time.Duration) error {
+	return st.client.Set(ctx, dedupeKeyPrefix+key, id, window).Err()
+}
+
+// Release 删除幂等键
+func (st *redisDedupeStore) Release(ctx context.Context, key string) error {
+	return st.client.Del(ctx, dedupeKeyPrefix+key).Err()
+}
+
+// dedupeEntry 内存中的一条幂等记录
+type dedupeEntry struct {
+	id      string
+	expires time.Time
+}
+
+// memoryDedupeStore 在进程内保存幂等记录，服务器重启后丢失
+type memoryDedupeStore struct {
+	mu        sync.Mutex
+	entries   map[string]dedupeEntry
+	lastSweep time.Time
+}
+
+// Reserve 占用幂等键，并定期清理过期的记录
+func (st *memoryDedupeStore) Reserve(ctx context.Context, key string) (string, bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(st.lastSweep) > dedupeSweep {
+		for k, e := range st.entries {
+			if now.After(e.expires) {
+				delete(st.entries, k)
+			}
+		}
+		st.lastSweep = now
+	}
+
+	if e, ok := st.entries[key]; ok && now.Before(e.expires) {
+		return e.id, true, nil
+	}
+	st.entries[key] = dedupeEntry{expires: now.Add(dedupeReserveTTL)}
+	return "", false, nil
+}
+
+// Commit 记录消息 ID
+func (st *memoryDedupeStore) Commit(ctx context.Context, key, id string, window time.Duration) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.entries[key] = dedupeEntry{id: id, expires: time.Now().Add(window)}
+	return nil
+}
+
+// Release 删除幂等键
+func (st *memoryDedupeStore) Release(ctx context.Context, key string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.entries, key)
+	return nil
+}
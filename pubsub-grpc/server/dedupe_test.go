@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// 首次发布仍在进行时重试返回 ABORTED，首次发布失败后重试可以再次发布，发布成功后重试返回首次的消息 ID
+func TestPublishDedupeInFlight(t *testing.T) {
+	s, _ := newTestServer(t, serverConfig{DedupeWindow: time.Minute})
+	ctx := context.Background()
+	req := &pb.PublishRequest{Topic: "orders", IdempotencyKey: "order-42", Payload: []byte("x")}
+	key, err := dedupeKey(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟另一个正在进行的发布
+	if _, dup, err := s.dedupe.Reserve(ctx, key); err != nil || dup {
+		t.Fatalf("占用幂等键返回 %v, %v", dup, err)
+	}
+	if _, _, err := s.publishMessage(ctx, req); status.Code(err) != codes.Aborted {
+		t.Fatalf("首次发布进行中时重试返回 %v，期望 Aborted", err)
+	}
+
+	// 首次发布失败，释放幂等键
+	if err := s.dedupe.Release(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	env, dup, err := s.publishMessage(ctx, req)
+	if err != nil || dup {
+		t.Fatalf("首次发布失败后重试返回 %v, %v", dup, err)
+	}
+	again, dup, err := s.publishMessage(ctx, req)
+	if err != nil || !dup || again.Id != env.Id {
+		t.Errorf("发布成功后重试返回 %v (%v, %v)，期望首次的消息 ID %s", again.GetId(), dup, err, env.Id)
+	}
+}
+
+// 发布中的幂等键在 dedupeReserveTTL 后过期，发布完成后保留整个去重窗口
+func TestMemoryDedupeReserveTTL(t *testing.T) {
+	ctx := context.Background()
+	st := &memoryDedupeStore{entries: make(map[string]dedupeEntry)}
+	st.Reserve(ctx, "pending")
+	if e := st.entries["pending"]; time.Until(e.expires) > dedupeReserveTTL {
+		t.Errorf("发布中的幂等键 %v 后过期，期望不超过 %v", time.Until(e.expires), dedupeReserveTTL)
+	}
+	st.Commit(ctx, "pending", "1", time.Hour)
+	if e := st.entries["pending"]; time.Until(e.expires) < 59*time.Minute {
+		t.Errorf("发布完成的幂等键 %v 后过期，期望保留去重窗口", time.Until(e.expires))
+	}
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+const (
+	streamField           = "envelope"             // Stream 条目中保存消息信封的字段
+	streamTextField       = "message"              // 旧版 Stream 条目中保存纯文本消息的字段
+	streamReadCount       = 10                     // 每次从 Stream 读取的最大消息数
+	streamBlock           = 1 * time.Second        // 读取 Stream 时的阻塞等待时间
+	groupFiltersKeyPrefix = "pubsub:group-filter:" // 记录主题中每个消费者组过滤条件的 Hash 键前缀，字段为消费者组名称
+)
+
+// publishPartitionedScript 将消息追加到主题 Stream，再以相同的 ID 追加到所属分区的 Stream。
+// 脚本原子执行，分区 Stream 中的 ID 与主题 Stream 一样递增；ARGV[1] 为 MAXLEN，0 表示不按数量裁剪
+var publishPartitionedScript = redis.NewScript(`
+local function xadd(key, id)
+  local args = {'XADD', key}
+  if tonumber(ARGV[1]) > 0 then
+    table.insert(args, 'MAXLEN')
+    table.insert(args, '~')
+    table.insert(args, ARGV[1])
+  end
+  table.insert(args, id)
+  table.insert(args, ARGV[2])
+  table.insert(args, ARGV[3])
+  return redis.call(unpack(args))
+end
+local id = xadd(KEYS[1], '*')
+xadd(KEYS[2], id)
+return id
+`)
+
+// publishDurable 将消息信封追加到主题对应的 Redis Stream，返回 Stream ID。
+// 启用分区时消息同时追加到所属分区的 Stream，消费者组从分区 Stream 读取。
+// 与历史 Stream 一样按 -retention 和 -retention-age 近似裁剪，超出保留范围的消息即使未确认也会被删除；
+// -retention 为 0 时不按数量裁剪
+func (s *pubSubServer) publishDurable(ctx context.Context, env *pb.Message) (string, error) {
+	data, err := encodeEnvelope(env)
+	if err != nil {
+		return "", err
+	}
+	maxLen := int64(max(s.retention.MaxMessages, 0))
+	streams := []string{env.Topic}
+	var id string
+	if s.partitions > 1 {
+		streams = append(streams, partitionStream(env.Topic, partitionOf(env, s.partitions)))
+		id, err = publishPartitionedScript.Run(ctx, s.redisClient, streams, maxLen, streamField, data).Text()
+	} else {
+		id, err = s.redisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: env.Topic,
+			MaxLen: maxLen,
+			Approx: true,
+			Values: map[string]any{streamField: data},
+		}).Result()
+	}
+	if err != nil {
+		return "", err
+	}
+	// 一次 XADD 只能使用一种裁剪策略，按时间裁剪单独执行，失败不影响已发布的消息
+	if s.retention.MaxAge > 0 {
+		minID := strconv.FormatInt(time.Now().Add(-s.retention.MaxAge).UnixMilli(), 10)
+		for _, stream := range streams {
+			if err := s.redisClient.XTrimMinIDApprox(ctx, stream, minID, 0).Err(); err != nil {
+				log.Printf("裁剪 Stream %s 的过期消息失败: %v", stream, err)
+			}
+		}
+	}
+	return id, nil
+}
+
+// subscribeDurable 从 Redis Stream 读取消息，指定消费者组时需要客户端确认
+func (s *pubSubServer) subscribeDurable(req *pb.SubscribeRequest, start startPosition, filter *messageFilter, stream pb.PubSub_SubscribeServer) error {
+	startID, err := s.streamStartID(stream.Context(), req.Topic, start)
+	if err != nil {
+		return status.Errorf(codes.Internal, "解析起始位置失败: %v", err)
+	}
+	s.trackDurable(req.Topic, 1)
+	defer s.trackDurable(req.Topic, -1)
+
+	if req.Group == "" {
+		// 从最新消息开始的订阅先收到主题当前的保留消息，消费者组中的订阅者不接收保留消息
+		if start.latest() {
+			if _, err := s.sendRetained(stream.Context(), []string{req.Topic}, filter, "", stream); err != nil {
+				return err
+			}
+		}
+		return s.tailStream(req, startID, filter, stream)
+	}
+	return s.consumeGroup(req, startID, filter, stream)
+}
+
+// streamStartID 将起始位置转换为 Stream ID，读取时返回该 ID 之后的条目
+func (s *pubSubServer) streamStartID(ctx context.Context, topic string, start startPosition) (string, error) {
+	switch start.kind {
+	case pb.StartPosition_START_EARLIEST:
+		return "0-0", nil
+	case pb.StartPosition_START_AFTER_ID:
+		return start.afterID, nil
+	case pb.StartPosition_START_FROM_TIME:
+		ms := start.since.UnixMilli()
+		if ms <= 0 {
+			return "0-0", nil
+		}
+		return fmt.Sprintf("%d-%d", ms-1, uint64(math.MaxUint64)), nil
+	case pb.StartPosition_START_LAST_N:
+		// 取倒数第 N+1 条的 ID，不足 N+1 条时从头开始
+		entries, err := s.redisClient.XRevRangeN(ctx, topic, "+", "-", int64(start.lastN)+1).Result()
+		if err != nil {
+			return "", err
+		}
+		if len(entries) <= start.lastN {
+			return "0-0", nil
+		}
+		return entries[start.lastN].ID, nil
+	default:
+		return "$", nil
+	}
+}
+
+// tailStream 不使用消费者组，从起始位置之后接收主题中满足过滤条件的消息
+func (s *pubSubServer) tailStream(req *pb.SubscribeRequest, startID string, filter *messageFilter, stream pb.PubSub_SubscribeServer) error {
+	ctx := stream.Context()
+	lastID := startID
+
+	log.Printf("客户端已订阅持久化主题: %s", req.Topic)
+
+	for ctx.Err() == nil {
+		// ACL 重新加载后被收回权限时结束订阅
+		if err := s.authorize(ctx, permSubscribe, req.Topic); err != nil {
+			return err
+		}
+		streams, err := s.redisClient.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{req.Topic, lastID},
+			Count:   streamReadCount,
+			Block:   streamBlock,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return status.Errorf(codes.Internal, "读取消息失败: %v", err)
+		}
+
+		for _, xs := range streams {
+			for _, msg := range xs.Messages {
+				lastID = msg.ID
+				env := streamEnvelope(req.Topic, msg)
+				if !filter.match(env) {
+					continue
+				}
+				if err := stream.Send(newSubscribeResponse(env)); err != nil {
+					return status.Errorf(codes.Internal, "发送消息失败: %v", err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// groupConsumer 消费者组中的一个订阅者
+type groupConsumer struct {
+	req               *pb.SubscribeRequest
+	consumer          string
+	filter            *messageFilter
+	visibilityTimeout time.Duration
+	maxAttempts       int64
+	deadLetterTopic   string
+	stream            pb.PubSub_SubscribeServer
+}
+
+// consumeGroup 以消费者组方式读取消息，超过可见性超时仍未确认的消息会重新投递给组内消费者
+// 消费者组不存在时从起始位置创建，已存在时沿用组的消费进度。
+// 过滤条件属于整个消费者组，不满足过滤条件的消息直接确认，不投递给该消费者组。
+// 启用分区时每个分区只投递给组内的一个订阅者，订阅者加入或离开时重新分配分区
+func (s *pubSubServer) consumeGroup(req *pb.SubscribeRequest, startID string, filter *messageFilter, stream pb.PubSub_SubscribeServer) error {
+	ctx := stream.Context()
+
+	c := &groupConsumer{
+		req:               req,
+		consumer:          req.Consumer,
+		filter:            filter,
+		visibilityTimeout: s.visibilityTimeout,
+		stream:            stream,
+	}
+	if c.consumer == "" {
+		c.consumer = newConsumerName()
+	}
+	if req.VisibilityTimeoutMs > 0 {
+		c.visibilityTimeout = time.Duration(req.VisibilityTimeoutMs) * time.Millisecond
+	}
+
+	// 创建消费者组，组已存在时忽略错误
+	groups := s.consumerGroups(req.Topic, req.Group)
+	created := false
+	for _, g := range groups {
+		err := s.redisClient.XGroupCreateMkStream(ctx, g.stream, g.name, startID).Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return status.Errorf(codes.Internal, "创建消费者组失败: %v", err)
+		}
+		created = created || err == nil
+	}
+	if err := s.bindGroupFilter(ctx, req.Topic, req.Group, filter, created); err != nil {
+		return err
+	}
+
+	c.maxAttempts, c.deadLetterTopic = s.deadLetterPolicy(req)
+	if c.maxAttempts > 0 && c.deadLetterTopic == req.Topic {
+		return status.Error(codes.InvalidArgument, "死信主题不能与订阅的主题相同")
+	}
+
+	log.Printf("消费者 %s 已加入主题 %s 的消费者组 %s", c.consumer, req.Topic, req.Group)
+	defer log.Printf("消费者 %s 已离开主题 %s 的消费者组 %s", c.consumer, req.Topic, req.Group)
+
+	if s.partitions <= 1 {
+		return s.consumeUnpartitioned(ctx, c, groups[0])
+	}
+	return s.consumePartitions(ctx, c)
+}
+
+// bindGroupFilter 将过滤条件绑定到消费者组。不满足过滤条件的消息在组内直接确认，
+// 组内订阅者使用不同的过滤条件时，一个订阅者会替其他订阅者丢弃消息，因此过滤条件属于整个消费者组：
+// 新建的组记录加入者的过滤条件，之后加入的订阅者必须使用相同的过滤条件
+func (s *pubSubServer) bindGroupFilter(ctx context.Context, topic, group string, filter *messageFilter, created bool) error {
+	expr := ""
+	if filter != nil {
+		expr = strings.TrimSpace(filter.expr)
+	}
+	key := groupFiltersKeyPrefix + topic
+	var err error
+	if created {
+		err = s.redisClient.HSet(ctx, key, group, expr).Err()
+	} else {
+		err = s.redisClient.HSetNX(ctx, key, group, expr).Err()
+	}
+	if err != nil {
+		return status.Errorf(codes.Internal, "记录消费者组的过滤条件失败: %v", err)
+	}
+	bound, err := s.redisClient.HGet(ctx, key, group).Result()
+	if err != nil {
+		return status.Errorf(codes.Internal, "读取消费者组的过滤条件失败: %v", err)
+	}
+	if bound != expr {
+		return status.Errorf(codes.FailedPrecondition, "消费者组 %s 的过滤条件为 %q，组内订阅者必须使用相同的过滤条件", group, bound)
+	}
+	return nil
+}
+
+// consumeUnpartitioned 从未分区的消费者组读取消息
+func (s *pubSubServer) consumeUnpartitioned(ctx context.Context, c *groupConsumer, g consumerGroup) error {
+	for ctx.Err() == nil {
+		// ACL 重新加载后被收回权限时结束订阅
+		if err := s.authorize(ctx, permSubscribe, c.req.Topic); err != nil {
+			return err
+		}
+		if err := s.redeliver(ctx, c, g, c.visibilityTimeout); err != nil {
+			return err
+		}
+		if _, err := s.readGroup(ctx, c, g, streamBlock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consumePartitions 从分配给该订阅者的分区 Stream 读取消息：先重新投递未确认的消息，再按顺序读取每个分区的新消息，
+// 所有分区都没有新消息时等待主题中出现新消息
+func (s *pubSubServer) consumePartitions(ctx context.Context, c *groupConsumer) error {
+	member := s.newPartitionMember(c.req.Topic, c.req.Group, c.consumer, c.visibilityTimeout)
+	if err := member.rebalance(ctx); err != nil {
+		return status.Errorf(codes.Internal, "分配分区失败: %v", err)
+	}
+	defer member.leave()
+	go member.run(ctx)
+
+	for ctx.Err() == nil {
+		if err := s.authorize(ctx, permSubscribe, c.req.Topic); err != nil {
+			return err
+		}
+
+		// 记录读取前主题的最新消息，之后若没有读到新消息，从它之后等待
+		lastID := "0-0"
+		latest, err := s.redisClient.XRevRangeN(ctx, c.req.Topic, "+", "-", 1).Result()
+		if err != nil && ctx.Err() == nil {
+			return status.Errorf(codes.Internal, "读取消息失败: %v", err)
+		}
+		if len(latest) > 0 {
+			lastID = latest[0].ID
+		}
+
+		read := 0
+		for _, op := range member.held() {
+			g := partitionGroup(c.req.Topic, c.req.Group, op.partition)
+			// 刚取得的分区立即认领之前持有者未确认的消息，保证它们先于新消息投递
+			minIdle := c.visibilityTimeout
+			if op.takeover {
+				minIdle = 0
+			}
+			if err := s.redeliver(ctx, c, g, minIdle); err != nil {
+				return err
+			}
+			if op.draining {
+				continue
+			}
+			n, err := s.readGroup(ctx, c, g, -1)
+			if err != nil {
+				return err
+			}
+			read += n
+		}
+		if read > 0 {
+			continue
+		}
+
+		err = s.redisClient.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{c.req.Topic, lastID},
+			Count:   1,
+			Block:   streamBlock,
+		}).Err()
+		if err != nil && !errors.Is(err, redis.Nil) && ctx.Err() == nil {
+			return status.Errorf(codes.Internal, "读取消息失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// redeliver 认领空闲超过 minIdle 的未确认消息并重新投递，超过最大投递次数的消息移入死信主题
+func (s *pubSubServer) redeliver(ctx context.Context, c *groupConsumer, g consumerGroup, minIdle time.Duration) error {
+	for start := "0-0"; ctx.Err() == nil; {
+		claimed, next, err := s.redisClient.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   g.stream,
+			Group:    g.name,
+			Consumer: c.consumer,
+			MinIdle:  minIdle,
+			Start:    start,
+			Count:    streamReadCount,
+		}).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return status.Errorf(codes.Internal, "认领未确认消息失败: %v", err)
+		}
+		if err := s.redeliverClaimed(ctx, c, g, claimed); err != nil {
+			return err
+		}
+		if next == "0-0" || len(claimed) == 0 {
+			break
+		}
+		start = next
+	}
+	return nil
+}
+
+// redeliverClaimed 重新投递认领到的消息
+func (s *pubSubServer) redeliverClaimed(ctx context.Context, c *groupConsumer, g consumerGroup, claimed []redis.XMessage) error {
+	for _, msg := range claimed {
+		// XAUTOCLAIM 已增加投递次数，超过最大投递次数的消息移入死信主题
+		entry, ok, err := s.pendingEntry(ctx, g.stream, g.name, msg.ID)
+		if err != nil {
+			log.Printf("查询消息 %s 的投递次数失败: %v", msg.ID, err)
+		}
+		attempts := entry.RetryCount
+		if ok && c.maxAttempts > 0 && attempts > c.maxAttempts {
+			if err := s.deadLetter(ctx, c, g, msg, attempts-1); err != nil {
+				log.Printf("移入死信主题失败: %v", err)
+			}
+			continue
+		}
+
+		env := streamEnvelope(c.req.Topic, msg)
+		if !c.filter.match(env) {
+			s.skipMessage(ctx, g, msg.ID)
+			continue
+		}
+		log.Printf("重新投递未确认消息 %s (主题 %s, 消费者组 %s, 第 %d 次投递)", msg.ID, c.req.Topic, g.name, attempts)
+		if err := s.sendGroupMessage(c, g, env, attempts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readGroup 读取消费者组中的新消息，block 为负数时不等待，返回读取的条目数
+func (s *pubSubServer) readGroup(ctx context.Context, c *groupConsumer, g consumerGroup, block time.Duration) (int, error) {
+	streams, err := s.redisClient.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    g.name,
+		Consumer: c.consumer,
+		Streams:  []string{g.stream, ">"},
+		Count:    streamReadCount,
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, status.Errorf(codes.Internal, "读取消息失败: %v", err)
+	}
+
+	n := 0
+	for _, xs := range streams {
+		for _, msg := range xs.Messages {
+			n++
+			env := streamEnvelope(c.req.Topic, msg)
+			if !c.filter.match(env) {
+				s.skipMessage(ctx, g, msg.ID)
+				continue
+			}
+			if err := s.sendGroupMessage(c, g, env, 1); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// sendGroupMessage 向订阅者发送消费者组中的消息
+func (s *pubSubServer) sendGroupMessage(c *groupConsumer, g consumerGroup, env *pb.Message, attempts int64) error {
+	resp := newSubscribeResponse(env)
+	resp.DeliveryAttempt = attempts
+	if g.partition >= 0 {
+		resp.Partition = int32(g.partition)
+	}
+	if err := c.stream.Send(resp); err != nil {
+		return status.Errorf(codes.Internal, "发送消息失败: %v", err)
+	}
+	return nil
+}
+
+// skipMessage 确认不满足消费者组过滤条件的消息，使其不会被重新投递
+func (s *pubSubServer) skipMessage(ctx context.Context, g consumerGroup, id string) {
+	if err := s.redisClient.XAck(ctx, g.stream, g.name, id).Err(); err != nil {
+		log.Printf("确认跳过的消息 %s 失败: %v", id, err)
+	}
+}
+
+// streamEnvelope 解析 Stream 条目中的消息信封，消息 ID 使用 Stream ID
+func streamEnvelope(topic string, msg redis.XMessage) *pb.Message {
+	var env *pb.Message
+	if data, ok := msg.Values[streamField].(string); ok {
+		env = decodeEnvelope(topic, data)
+	} else {
+		text, _ := msg.Values[streamTextField].(string)
+		env = decodeEnvelope(topic, text)
+	}
+	env.Id = msg.ID
+	return env
+}
+
+// Ack 确认消费者组已处理的消息，确认后的消息不再重新投递
+func (s *pubSubServer) Ack(ctx context.Context, req *pb.AckRequest) (*pb.AckResponse, error) {
+	if !s.durable {
+		return nil, status.Error(codes.FailedPrecondition, "消息确认仅在持久化模式下可用")
+	}
+	if req.Topic == "" || req.Group == "" {
+		return nil, status.Error(codes.InvalidArgument, "必须指定主题和消费者组")
+	}
+	if err := s.authorize(ctx, permSubscribe, req.Topic); err != nil {
+		return nil, err
+	}
+	if len(req.Ids) == 0 {
+		return &pb.AckResponse{}, nil
+	}
+
+	// 启用分区时消息只在所属分区 Stream 的组中待确认，在其他分区的组中确认不产生影响
+	pipe := s.redisClient.Pipeline()
+	var acks []*redis.IntCmd
+	for _, g := range s.consumerGroups(req.Topic, req.Group) {
+		acks = append(acks, pipe.XAck(ctx, g.stream, g.name, req.Ids...))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, status.Errorf(codes.Internal, "确认消息失败: %v", err)
+	}
+	var n int64
+	for _, ack := range acks {
+		n += ack.Val()
+	}
+	s.redisClient.HDel(ctx, errorsKey(req.Topic, req.Group), req.Ids...)
+	return &pb.AckResponse{AckedCount: int32(n)}, nil
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// messageFilter 按消息头过滤消息的表达式，语法示例：
+//
+//	region = "eu" AND (priority >= 5 OR type IN ("alert", "alarm")) AND NOT source PREFIX "test-"
+//
+// 比较运算符为 =（或 ==）、!=、<、<=、>、>=、IN 和 PREFIX，可以用 AND、OR、NOT 和括号组合，
+// 关键字不区分大小写。消息头不存在时除 != 外的比较都不成立
+type messageFilter struct {
+	expr string
+	root filterNode
+}
+
+// filterNode 过滤表达式的节点
+type filterNode interface {
+	match(headers map[string]string) bool
+}
+
+// parseFilter 解析订阅的过滤表达式，表达式为空时返回 nil 表示不过滤
+func parseFilter(expr string) (*messageFilter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "无效的过滤表达式: %v", err)
+	}
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = fmt.Errorf("位置 %d 处有多余的内容 %q", p.peek().pos, p.peek().text)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "无效的过滤表达式: %v", err)
+	}
+	return &messageFilter{expr: expr, root: root}, nil
+}
+
+// match 判断消息是否满足过滤条件，过滤器为 nil 时总是满足
+func (f *messageFilter) match(env *pb.Message) bool {
+	if f == nil {
+		return true
+	}
+	return f.root.match(env.Headers)
+}
+
+// andNode 所有子条件都成立
+type andNode []filterNode
+
+func (n andNode) match(headers map[string]string) bool {
+	for _, c := range n {
+		if !c.match(headers) {
+			return false
+		}
+	}
+	return true
+}
+
+// orNode 任一子条件成立
+type orNode []filterNode
+
+func (n orNode) match(headers map[string]string) bool {
+	for _, c := range n {
+		if c.match(headers) {
+			return true
+		}
+	}
+	return false
+}
+
+// notNode 子条件不成立
+type notNode struct{ node filterNode }
+
+func (n notNode) match(headers map[string]string) bool {
+	return !n.node.match(headers)
+}
+
+// filterValue 表达式中的字面量
+type filterValue struct {
+	text    string
+	num     float64
+	numeric bool // 是否为数字字面量
+}
+
+// equal 比较消息头的值，数字字面量与可以解析为数字的值按数值比较
+func (v filterValue) equal(s string) bool {
+	if v.numeric {
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n == v.num
+		}
+	}
+	return s == v.text
+}
+
+// compareNode 消息头与字面量的比较
+type compareNode struct {
+	header string
+	op     string
+	values []filterValue
+}
+
+func (n compareNode) match(headers map[string]string) bool {
+	s, ok := headers[n.header]
+	if !ok {
+		return n.op == "!="
+	}
+
+	switch n.op {
+	case "=":
+		return n.values[0].equal(s)
+	case "!=":
+		return !n.values[0].equal(s)
+	case "IN":
+		for _, v := range n.values {
+			if v.equal(s) {
+				return true
+			}
+		}
+		return false
+	case "PREFIX":
+		return strings.HasPrefix(s, n.values[0].text)
+	}
+
+	// 数值比较，消息头不是数字时不成立
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return false
+	}
+	y := n.values[0].num
+	switch n.op {
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	case ">=":
+		return x >= y
+	}
+	return false
+}
+
+// filterTokenKind 词法单元类型
+type filterTokenKind int
+
+const (
+	tokenEOF filterTokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+// filterToken 词法单元
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	pos  int
+}
+
+// tokenizeFilter 将过滤表达式切分为词法单元
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, filterToken{kind: tokenComma, text: ",", pos: i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("位置 %d 处的字符串没有结束引号", start)
+			}
+			i++
+			tokens = append(tokens, filterToken{kind: tokenString, text: sb.String(), pos: start})
+		case strings.ContainsRune("=!<>", r):
+			start := i
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("位置 %d 处的运算符 ! 无效，请使用 != 或 NOT", start)
+			}
+			i += len(op)
+			tokens = append(tokens, filterToken{kind: tokenOp, text: op, pos: start})
+		case r == '-' || r == '+' || unicode.IsDigit(r):
+			start := i
+			for i++; i < len(runes) && (unicode.IsDigit(runes[i]) || strings.ContainsRune(".eE+-", runes[i])); i++ {
+			}
+			text := string(runes[start:i])
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, fmt.Errorf("位置 %d 处的数字 %q 无效", start, text)
+			}
+			tokens = append(tokens, filterToken{kind: tokenNumber, text: text, pos: start})
+		case isIdentRune(r):
+			start := i
+			for i < len(runes) && isIdentRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		default:
+			return nil, fmt.Errorf("位置 %d 处有无效字符 %q", i, r)
+		}
+	}
+	return append(tokens, filterToken{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// isIdentRune 消息头名称中允许的字符
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.:/", r)
+}
+
+// filterParser 递归下降解析过滤表达式
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword 当前词法单元是否为指定关键字，是则跳过
+func (p *filterParser) keyword(kw string) bool {
+	t := p.peek()
+	if t.kind == tokenIdent && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// parseOr 解析 a OR b OR ...
+func (p *filterParser) parseOr() (filterNode, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	nodes := orNode{node}
+	for p.keyword("OR") {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+// parseAnd 解析 a AND b AND ...
+func (p *filterParser) parseAnd() (filterNode, error) {
+	node, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	nodes := andNode{node}
+	for p.keyword("AND") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+// parseUnary 解析 NOT 条件、括号表达式或比较
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.keyword("NOT") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	if p.peek().kind == tokenLParen {
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRParen {
+			return nil, fmt.Errorf("位置 %d 处缺少 )", t.pos)
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison 解析 消息头 运算符 值
+func (p *filterParser) parseComparison() (filterNode, error) {
+	t := p.next()
+	if t.kind != tokenIdent && t.kind != tokenString {
+		return nil, fmt.Errorf("位置 %d 处应为消息头名称", t.pos)
+	}
+	node := compareNode{header: t.text}
+
+	switch op := p.next(); {
+	case op.kind == tokenOp && op.text == "==":
+		node.op = "="
+	case op.kind == tokenOp:
+		node.op = op.text
+	case op.kind == tokenIdent && (strings.EqualFold(op.text, "IN") || strings.EqualFold(op.text, "PREFIX")):
+		node.op = strings.ToUpper(op.text)
+	default:
+		return nil, fmt.Errorf("位置 %d 处应为比较运算符 (=、!=、<、<=、>、>=、IN、PREFIX)", op.pos)
+	}
+
+	if node.op == "IN" {
+		if t := p.next(); t.kind != tokenLParen {
+			return nil, fmt.Errorf("位置 %d 处 IN 之后应为 (", t.pos)
+		}
+		for {
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			node.values = append(node.values, v)
+			t := p.next()
+			if t.kind == tokenRParen {
+				break
+			}
+			if t.kind != tokenComma {
+				return nil, fmt.Errorf("位置 %d 处应为 , 或 )", t.pos)
+			}
+		}
+		return node, nil
+	}
+
+	pos := p.peek().pos
+	v, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	switch node.op {
+	case "<", "<=", ">", ">=":
+		if !v.numeric {
+			return nil, fmt.Errorf("位置 %d 处 %s 的比较值必须是数字", pos, node.op)
+		}
+	case "PREFIX":
+		v.numeric = false
+	}
+	node.values = []filterValue{v}
+	return node, nil
+}
+
+// parseValue 解析字符串或数字字面量，不带引号的单词按字符串处理
+func (p *filterParser) parseValue() (filterValue, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString, tokenIdent:
+		return filterValue{text: t.text}, nil
+	case tokenNumber:
+		n, _ := strconv.ParseFloat(t.text, 64)
+		return filterValue{text: t.text, num: n, numeric: true}, nil
+	default:
+		return filterValue{}, fmt.Errorf("位置 %d 处应为字符串或数字", t.pos)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+func TestFilterMatch(t *testing.T) {
+	headers := map[string]string{
+		"region":       "eu",
+		"priority":     "7",
+		"type":         "alert",
+		"source":       "test-runner",
+		"x-trace.id":   "a:b/c",
+		"display name": "Alice",
+		"quote":        `say "hi"`,
+		"path":         `C:\temp`,
+		"count":        "1e3",
+		"empty":        "",
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// 比较运算符
+		{`region = "eu"`, true},
+		{`region == eu`, true},
+		{`region != "us"`, true},
+		{`region != "eu"`, false},
+		{`priority >= 7`, true},
+		{`priority > 7`, false},
+		{`priority < 10.5`, true},
+		{`priority <= 6`, false},
+		{`priority = 7.0`, true},
+		{`count = 1000`, true},
+		{`region < 5`, false},
+		{`type IN ("alarm", "alert")`, true},
+		{`type in (alarm, "warning")`, false},
+		{`priority IN (1, 7)`, true},
+		{`source PREFIX "test-"`, true},
+		{`source prefix "prod-"`, false},
+		{`empty = ""`, true},
+
+		// 优先级：NOT 高于 AND，AND 高于 OR
+		{`region = "us" AND priority = 7 OR type = "alert"`, true},
+		{`region = "us" AND (priority = 7 OR type = "alert")`, false},
+		{`type = "alert" OR region = "us" AND priority = 1`, true},
+		{`(type = "alert" OR region = "us") AND priority = 1`, false},
+		{`NOT region = "us" AND priority = 7`, true},
+		{`NOT (region = "eu" AND priority = 7)`, false},
+		{`NOT NOT region = "eu"`, true},
+		{`region = "eu" and not source prefix "test-"`, false},
+
+		// 引号和转义
+		{`region = 'eu'`, true},
+		{`quote = "say \"hi\""`, true},
+		{`quote = 'say "hi"'`, true},
+		{`"display name" = "Alice"`, true},
+		{`region = "e\u"`, true}, // 反斜杠转义任意字符
+		{`path = "C:\\temp"`, true},
+		{`path = "C:\temp"`, false},
+
+		// 消息头名称中的特殊字符
+		{`x-trace.id = "a:b/c"`, true},
+		{`'x-trace.id' PREFIX "a:"`, true},
+
+		// 不存在的消息头：除 != 外的比较都不成立
+		{`missing = ""`, false},
+		{`missing != "x"`, true},
+		{`missing IN ("a", "b")`, false},
+		{`missing PREFIX ""`, false},
+		{`missing < 100`, false},
+		{`NOT missing = "x"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := parseFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+			if got := f.match(&pb.Message{Headers: headers}); got != tt.want {
+				t.Errorf("match = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterEmpty(t *testing.T) {
+	for _, expr := range []string{"", "   ", "\t\n"} {
+		f, err := parseFilter(expr)
+		if err != nil || f != nil {
+			t.Errorf("parseFilter(%q) = %v, %v，期望不过滤", expr, f, err)
+		}
+		if !f.match(&pb.Message{}) {
+			t.Errorf("空过滤条件应匹配所有消息")
+		}
+	}
+}
+
+func TestFilterInvalid(t *testing.T) {
+	tests := []string{
+		`region`,
+		`region =`,
+		`= "eu"`,
+		`region = "eu`,
+		`region = 'eu`,
+		`region = "eu\`,
+		`region ! "eu"`,
+		`region ~ "eu"`,
+		`priority > "high"`,
+		`priority >= eu`,
+		`priority = 1e`,
+		`priority = --1`,
+		`type IN "a"`,
+		`type IN ()`,
+		`type IN ("a" "b")`,
+		`type IN ("a",`,
+		`(region = "eu"`,
+		`region = "eu")`,
+		`region = "eu" AND`,
+		`OR region = "eu"`,
+		`NOT`,
+		`region = "eu" region = "us"`,
+		`region = "eu" XOR type = "a"`,
+		`()`,
+		`,`,
+	}
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			f, err := parseFilter(expr)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("parseFilter = %v, %v，期望 InvalidArgument", f, err)
+			}
+		})
+	}
+}
+
+// 无效的过滤表达式使订阅以 INVALID_ARGUMENT 失败
+func TestSubscribeInvalidFilter(t *testing.T) {
+	s, _ := newTestServer(t, serverConfig{})
+	for _, expr := range []string{`region = "eu`, `type IN (`, `priority > x`} {
+		errc := startSubscribe(context.Background(), s, &pb.SubscribeRequest{Topic: "orders", Filter: expr}, func(*pb.SubscribeResponse) error { return nil })
+		select {
+		case err := <-errc:
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("过滤条件 %q 的订阅返回 %v，期望 InvalidArgument", expr, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("过滤条件 %q 无效时订阅没有结束", expr)
+		}
+	}
+}
+
+// 订阅者只收到满足过滤条件的消息
+func TestSubscribeFilter(t *testing.T) {
+	s, broker := newTestServer(t, serverConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan *pb.Message, 10)
+	startSubscribe(ctx, s, &pb.SubscribeRequest{Topic: "orders", Filter: `region = "eu" AND priority >= 5`}, func(resp *pb.SubscribeResponse) error {
+		if resp.Envelope != nil {
+			received <- resp.Envelope
+		}
+		return nil
+	})
+	waitSubscribers(t, broker, "orders", 1)
+
+	for _, h := range []map[string]string{
+		{"region": "us", "priority": "9"},
+		{"region": "eu", "priority": "1"},
+		{"region": "eu"},
+		{"region": "eu", "priority": "5"},
+	} {
+		if _, _, err := s.publishMessage(ctx, &pb.PublishRequest{Topic: "orders", Headers: h}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case env := <-received:
+		if env.Headers["priority"] != "5" {
+			t.Errorf("收到不满足过滤条件的消息: %v", env.Headers)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("没有收到满足过滤条件的消息")
+	}
+	select {
+	case env := <-received:
+		t.Errorf("收到多余的消息: %v", env.Headers)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
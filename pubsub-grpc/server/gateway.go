@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// maxHTTPPublishSize HTTP 发布的最大消息大小，与 gRPC 默认的最大接收消息大小一致
+const maxHTTPPublishSize = 4 << 20
+
+// gatewayJSON 网关输出 JSON 的编码方式，字段名与 subscriber -output=json 一致
+var gatewayJSON = protojson.MarshalOptions{UseProtoNames: true}
+
+// gateway 为浏览器等不能使用 gRPC 流的客户端提供 HTTP 接口：
+// Server-Sent Events 和 WebSocket 订阅、HTTP 发布，内部调用与 gRPC 相同的订阅和发布实现
+type gateway struct {
+	server *pubSubServer
+	// allowedOrigins 允许建立 WebSocket 连接的页面来源，为空时只允许与网关同源的页面，
+	// 包含 "*" 时允许所有来源
+	allowedOrigins []string
+}
+
+// newGatewayHandler 创建 HTTP 网关的路由
+func newGatewayHandler(s *pubSubServer, allowedOrigins []string) http.Handler {
+	g := &gateway{server: s, allowedOrigins: allowedOrigins}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /topics/{topic}/events", g.handleEvents)
+	mux.HandleFunc("GET /topics/{topic}/ws", g.handleWebSocket)
+	mux.HandleFunc("POST /topics/{topic}", g.handlePublish)
+	return mux
+}
+
+// gatewayStream 实现订阅的服务端流，将 Subscribe 发送的每条响应交给 HTTP 连接
+type gatewayStream struct {
+	ctx  context.Context
+	send func(*pb.SubscribeResponse) error
+}
+
+var _ pb.PubSub_SubscribeServer = (*gatewayStream)(nil)
+
+func (st *gatewayStream) Send(resp *pb.SubscribeResponse) error { return st.send(resp) }
+func (st *gatewayStream) Context() context.Context              { return st.ctx }
+func (st *gatewayStream) SetHeader(metadata.MD) error           { return nil }
+func (st *gatewayStream) SendHeader(metadata.MD) error          { return nil }
+func (st *gatewayStream) SetTrailer(metadata.MD)                {}
+func (st *gatewayStream) SendMsg(m any) error                   { return st.send(m.(*pb.SubscribeResponse)) }
+func (st *gatewayStream) RecvMsg(m any) error                   { return io.EOF }
+
+// authenticate 启用访问控制时识别调用方。浏览器的 EventSource 和 WebSocket 不能设置请求头，
+// 因此除 Authorization: Bearer <令牌> 外也接受查询参数 access_token。查询参数会出现在
+// 代理和访问日志中，两者都存在时以请求头为准
+func (g *gateway) authenticate(r *http.Request) (context.Context, error) {
+	ctx := r.Context()
+	if g.server.acl == nil {
+		return ctx, nil
+	}
+	auth := r.Header.Get("Authorization")
+	if token := r.URL.Query().Get("access_token"); auth == "" && token != "" {
+		auth = "Bearer " + token
+	}
+	if auth != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", auth))
+	}
+	return g.server.acl.authenticate(ctx)
+}
+
+// subscribeRequest 根据 HTTP 请求构造订阅请求：路径中的主题加上查询参数 topic 指定的其他主题或模式，
+// 查询参数 filter 为过滤表达式，last_n 回放最近的消息；带有 Last-Event-ID（请求头或查询参数 last_event_id）时从该消息之后续订
+func subscribeRequest(r *http.Request) (*pb.SubscribeRequest, error) {
+	q := r.URL.Query()
+	req := &pb.SubscribeRequest{
+		Topics: append([]string{r.PathValue("topic")}, q["topic"]...),
+		Filter: q.Get("filter"),
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = q.Get("last_event_id")
+	}
+	switch {
+	case lastID != "":
+		req.StartPosition = pb.StartPosition_START_AFTER_ID
+		req.StartId = lastID
+	case q.Has("last_n"):
+		n, err := strconv.Atoi(q.Get("last_n"))
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "无效的 last_n: %q", q.Get("last_n"))
+		}
+		req.StartPosition = pb.StartPosition_START_LAST_N
+		req.LastN = int32(n)
+	}
+	if _, err := parseStartPosition(req); err != nil {
+		return nil, err
+	}
+	if _, err := parseFilter(req.Filter); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// prepareSubscribe 认证调用方并校验订阅参数和权限，使错误在建立事件流之前以 HTTP 状态码返回
+func (g *gateway) prepareSubscribe(r *http.Request) (context.Context, *pb.SubscribeRequest, error) {
+	ctx, err := g.authenticate(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := subscribeRequest(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := g.server.authorize(ctx, permSubscribe, req.Topics...); err != nil {
+		return nil, nil, err
+	}
+	return ctx, req, nil
+}
+
+// handleEvents 以 Server-Sent Events 推送订阅的消息。每个事件的 id 为消息 ID，
+// 浏览器重新连接时通过 Last-Event-ID 从断开处继续；心跳以注释行发送
+func (g *gateway) handleEvents(w http.ResponseWriter, r *http.Request) {
+	ctx, req, err := g.prepareSubscribe(r)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "连接不支持流式响应", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	stream := &gatewayStream{ctx: ctx, send: func(resp *pb.SubscribeResponse) error {
+		if resp.Heartbeat {
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			flusher.Flush()
+			return err
+		}
+		data, err := gatewayJSON.Marshal(resp)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %s\ndata: %s\n\n", resp.Id, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}}
+	if err := g.server.Subscribe(req, stream); err != nil && ctx.Err() == nil {
+		// 事件流已经建立，错误以 error 事件发送
+		data, _ := json.Marshal(httpError(err))
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+		flusher.Flush()
+	}
+}
+
+// checkOrigin 在 WebSocket 握手时校验页面来源。浏览器跨站发起 WebSocket 连接时会带上 Cookie 等凭据，
+// 且不受同源策略限制，因此只接受同源或 allowedOrigins 中的页面；没有 Origin 请求头的非浏览器客户端不受限制
+func (g *gateway) checkOrigin(config *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	if slices.Contains(g.allowedOrigins, "*") || slices.Contains(g.allowedOrigins, origin) {
+		return nil
+	}
+	if u, err := url.Parse(origin); err == nil && len(g.allowedOrigins) == 0 && u.Host == r.Host {
+		return nil
+	}
+	log.Printf("拒绝来源 %s 的 WebSocket 连接 (%s)", origin, r.RemoteAddr)
+	return fmt.Errorf("不允许来源 %s", origin)
+}
+
+// handleWebSocket 通过 WebSocket 推送订阅的消息，每条订阅响应（包括心跳）为一个 JSON 文本帧。
+// 浏览器不能为 WebSocket 设置请求头，续订使用查询参数 last_event_id；页面来源不被允许时握手返回 403
+func (g *gateway) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	ctx, req, err := g.prepareSubscribe(r)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	websocket.Server{Handshake: g.checkOrigin, Handler: func(ws *websocket.Conn) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		// 客户端不发送消息，读取失败说明连接已关闭
+		go func() {
+			defer cancel()
+			io.Copy(io.Discard, ws)
+		}()
+
+		stream := &gatewayStream{ctx: ctx, send: func(resp *pb.SubscribeResponse) error {
+			data, err := gatewayJSON.Marshal(resp)
+			if err != nil {
+				return err
+			}
+			return websocket.Message.Send(ws, string(data))
+		}}
+		if err := g.server.Subscribe(req, stream); err != nil && ctx.Err() == nil {
+			data, _ := json.Marshal(httpError(err))
+			websocket.Message.Send(ws, string(data))
+		}
+	}}.ServeHTTP(w, r)
+}
+
+// handlePublish 将请求体作为消息内容发布到主题，Content-Type 为消息内容类型。
+// 查询参数 header（key=value，可重复）、ordering_key、retain、delay 和 publisher_id 对应发布请求的字段，
+// 请求头 Idempotency-Key 为幂等键。返回 JSON 格式的发布确认
+func (g *gateway) handlePublish(w http.ResponseWriter, r *http.Request) {
+	ctx, err := g.authenticate(r)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPPublishSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("消息超过 %d 字节", maxHTTPPublishSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeHTTPError(w, status.Errorf(codes.InvalidArgument, "读取请求体失败: %v", err))
+		return
+	}
+	req, err := publishRequest(r, payload)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	env, duplicate, err := g.server.publishMessage(ctx, req)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	data, err := gatewayJSON.Marshal(&pb.PublishAck{
+		MessageId: env.Id,
+		Scheduled: env.DeliverAt != nil,
+		Duplicate: duplicate,
+	})
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
+
+// publishRequest 根据 HTTP 请求构造发布请求
+func publishRequest(r *http.Request, payload []byte) (*pb.PublishRequest, error) {
+	var err error
+	q := r.URL.Query()
+	req := &pb.PublishRequest{
+		Topic:          r.PathValue("topic"),
+		PublisherId:    q.Get("publisher_id"),
+		ContentType:    r.Header.Get("Content-Type"),
+		Payload:        payload,
+		OrderingKey:    q.Get("ordering_key"),
+		IdempotencyKey: r.Header.Get("Idempotency-Key"),
+	}
+	if req.PublisherId == "" {
+		req.PublisherId = r.RemoteAddr
+	}
+	if headers := q["header"]; len(headers) > 0 {
+		req.Headers = make(map[string]string, len(headers))
+		for _, h := range headers {
+			k, v, ok := strings.Cut(h, "=")
+			if !ok || k == "" {
+				return nil, status.Errorf(codes.InvalidArgument, "消息头的格式应为 key=value: %q", h)
+			}
+			req.Headers[k] = v
+		}
+	}
+	if s := q.Get("retain"); s != "" {
+		if req.Retain, err = strconv.ParseBool(s); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "无效的 retain: %q", s)
+		}
+	}
+	if s := q.Get("delay"); s != "" {
+		delay, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "无效的 delay: %q", s)
+		}
+		req.DelayMs = delay.Milliseconds()
+	}
+	return req, nil
+}
+
+// httpErrorBody 网关返回的错误
+type httpErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// httpError 将 gRPC 错误转换为网关返回的错误内容
+func httpError(err error) httpErrorBody {
+	st := status.Convert(err)
+	return httpErrorBody{Code: st.Code().String(), Message: st.Message()}
+}
+
+// writeHTTPError 以 JSON 返回错误，HTTP 状态码由 gRPC 状态码转换而来
+func writeHTTPError(w http.ResponseWriter, err error) {
+	data, _ := json.Marshal(httpError(err))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(status.Code(err)))
+	w.Write(append(data, '\n'))
+}
+
+// httpStatus 将 gRPC 状态码转换为 HTTP 状态码
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.Canceled:
+		return 499
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// serveGateway 在指定端口上运行 HTTP 网关
+func serveGateway(port int, s *pubSubServer, allowedOrigins []string) {
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           newGatewayHandler(s, allowedOrigins),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("HTTP 网关运行在端口 %d", port)
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatalf("HTTP 网关运行失败: %v", err)
+	}
+}
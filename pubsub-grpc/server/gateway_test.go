@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// newTestGateway 启动 HTTP 网关，返回网关地址
+func newTestGateway(t *testing.T, s *pubSubServer, allowedOrigins ...string) string {
+	t.Helper()
+	srv := httptest.NewServer(newGatewayHandler(s, allowedOrigins))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// gatewayRequest 发送 HTTP 请求，token 不为空时通过 Authorization 请求头传递
+func gatewayRequest(t *testing.T, method, url, token, body string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+// HTTP 发布的请求体、内容类型和查询参数对应消息的字段，返回发布确认
+func TestGatewayPublish(t *testing.T) {
+	s, broker := newTestServer(t, serverConfig{})
+	url := newTestGateway(t, s)
+	sub, err := broker.Subscribe(context.Background(), "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, url+"/topics/orders?header=region=eu&ordering_key=k1", strings.NewReader(`{"id": 42}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("发布返回 %s", resp.Status)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	ack := &pb.PublishAck{}
+	if err := protojson.Unmarshal(data, ack); err != nil {
+		t.Fatalf("解析发布确认 %s 失败: %v", data, err)
+	}
+
+	env := receive(t, sub).Message
+	if env.Id != ack.MessageId {
+		t.Errorf("发布确认的消息 ID 为 %s，收到的消息 ID 为 %s", ack.MessageId, env.Id)
+	}
+	if string(env.Payload) != `{"id": 42}` || env.ContentType != "application/json" {
+		t.Errorf("收到的消息内容为 %q (%s)", env.Payload, env.ContentType)
+	}
+	if env.Headers["region"] != "eu" || env.OrderingKey != "k1" {
+		t.Errorf("收到的消息头为 %v，排序键为 %q", env.Headers, env.OrderingKey)
+	}
+
+	for _, query := range []string{"header=region", "retain=maybe", "delay=soon"} {
+		if resp, body := gatewayRequest(t, http.MethodPost, url+"/topics/orders?"+query, "", "x"); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("查询参数 %s 返回 %s，期望 400: %s", query, resp.Status, body)
+		}
+	}
+}
+
+// Server-Sent Events 订阅以消息 ID 作为事件 id 推送 JSON 格式的订阅响应
+func TestGatewayEvents(t *testing.T) {
+	s, broker := newTestServer(t, serverConfig{})
+	url := newTestGateway(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url+"/topics/orders/events?filter=region%3D%22eu%22", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("订阅返回 %s (%s)", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	waitSubscribers(t, broker, "orders", 1)
+
+	if _, _, err := s.publishMessage(ctx, &pb.PublishRequest{Topic: "orders", Headers: map[string]string{"region": "us"}}); err != nil {
+		t.Fatal(err)
+	}
+	env, _, err := s.publishMessage(ctx, &pb.PublishRequest{Topic: "orders", Headers: map[string]string{"region": "eu"}, Payload: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	var id, data string
+	for id == "" || data == "" {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("读取事件失败: %v", err)
+		}
+		if v, ok := strings.CutPrefix(line, "id: "); ok {
+			id = strings.TrimSpace(v)
+		}
+		if v, ok := strings.CutPrefix(line, "data: "); ok {
+			data = strings.TrimSpace(v)
+		}
+	}
+	if id != env.Id {
+		t.Errorf("事件 id 为 %s，期望满足过滤条件的消息 %s", id, env.Id)
+	}
+	got := &pb.SubscribeResponse{}
+	if err := protojson.Unmarshal([]byte(data), got); err != nil {
+		t.Fatalf("解析事件数据 %s 失败: %v", data, err)
+	}
+	if got.Topic != "orders" || string(got.Envelope.GetPayload()) != "hello" {
+		t.Errorf("事件数据为 %s", data)
+	}
+
+	// 订阅参数无效时在建立事件流之前返回 400
+	if resp, body := gatewayRequest(t, http.MethodGet, url+"/topics/orders/events?filter=region%3D", "", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("无效的过滤条件返回 %s，期望 400: %s", resp.Status, body)
+	}
+}
+
+// 启用访问控制时，没有令牌返回 401，没有主题权限返回 403，请求头中的令牌优先于查询参数
+func TestGatewayAuth(t *testing.T) {
+	s, _ := newTestServer(t, serverConfig{ACL: newTestACL(t)})
+	url := newTestGateway(t, s)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"发布时没有令牌", http.MethodPost, "/topics/orders", "", http.StatusUnauthorized},
+		{"发布时令牌无效", http.MethodPost, "/topics/orders", "nobody", http.StatusUnauthorized},
+		{"没有发布权限", http.MethodPost, "/topics/orders", "billing-token", http.StatusForbidden},
+		{"有发布权限", http.MethodPost, "/topics/orders", "alice-token", http.StatusOK},
+		{"查询参数中的令牌", http.MethodPost, "/topics/orders?access_token=alice-token", "", http.StatusOK},
+		{"请求头优先于查询参数", http.MethodPost, "/topics/orders?access_token=alice-token", "billing-token", http.StatusForbidden},
+		{"订阅时没有令牌", http.MethodGet, "/topics/orders/events", "", http.StatusUnauthorized},
+		{"没有订阅权限", http.MethodGet, "/topics/orders/events", "mallory-token", http.StatusForbidden},
+		{"附加的主题没有订阅权限", http.MethodGet, "/topics/orders/events?topic=users", "alice-token", http.StatusForbidden},
+		{"WebSocket 没有令牌", http.MethodGet, "/topics/orders/ws", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := gatewayRequest(t, tt.method, url+tt.path, tt.token, "x")
+			if resp.StatusCode != tt.status {
+				t.Fatalf("返回 %s，期望 %d: %s", resp.Status, tt.status, body)
+			}
+			if tt.status != http.StatusOK {
+				var e httpErrorBody
+				if err := json.Unmarshal([]byte(body), &e); err != nil || e.Code == "" {
+					t.Errorf("错误内容 %q 不是 JSON 格式的错误", body)
+				}
+			}
+		})
+	}
+}
+
+func TestGatewayCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		ok      bool
+	}{
+		{"非浏览器客户端", nil, "", true},
+		{"同源页面", nil, "http://gateway.local:8080", true},
+		{"其他站点", nil, "https://evil.example", false},
+		{"同名主机的其他端口", nil, "http://gateway.local:9090", false},
+		{"允许列表中的来源", []string{"https://dash.example.com"}, "https://dash.example.com", true},
+		{"配置允许列表后同源页面也需要加入列表", []string{"https://dash.example.com"}, "http://gateway.local:8080", false},
+		{"允许所有来源", []string{"*"}, "https://evil.example", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &gateway{allowedOrigins: tt.allowed}
+			r := httptest.NewRequest(http.MethodGet, "http://gateway.local:8080/topics/orders/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if err := g.checkOrigin(nil, r); (err == nil) != tt.ok {
+				t.Errorf("来源 %q 的校验结果为 %v", tt.origin, err)
+			}
+		})
+	}
+}
+
+// 允许的来源可以通过 WebSocket 订阅，不允许的来源握手失败
+func TestGatewayWebSocket(t *testing.T) {
+	s, broker := newTestServer(t, serverConfig{})
+	url := newTestGateway(t, s, "https://dash.example.com")
+	wsURL := "ws" + strings.TrimPrefix(url, "http") + "/topics/orders/ws"
+
+	if ws, err := websocket.Dial(wsURL, "", "https://evil.example"); err == nil {
+		ws.Close()
+		t.Fatal("不允许的来源建立了 WebSocket 连接")
+	}
+
+	ws, err := websocket.Dial(wsURL, "", "https://dash.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	waitSubscribers(t, broker, "orders", 1)
+	env, _, err := s.publishMessage(context.Background(), &pb.PublishRequest{Topic: "orders", Payload: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var frame string
+	if err := websocket.Message.Receive(ws, &frame); err != nil {
+		t.Fatal(err)
+	}
+	got := &pb.SubscribeResponse{}
+	if err := protojson.Unmarshal([]byte(frame), got); err != nil {
+		t.Fatalf("解析帧 %s 失败: %v", frame, err)
+	}
+	if got.Id != env.Id {
+		t.Errorf("收到消息 %s，期望 %s", got.Id, env.Id)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// heartbeatStream 包装订阅流，串行化发送并在空闲时发送心跳，
+// 使服务端能及时发现已断开的客户端，客户端也能据此判断连接是否存活
+type heartbeatStream struct {
+	pb.PubSub_SubscribeServer
+
+	mu       sync.Mutex
+	lastSend time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startHeartbeat 开始在空闲的订阅流上发送心跳，interval 不为正数时不发送心跳。
+// 返回的流用于替代原始流发送消息，处理函数返回前必须调用 stop
+func startHeartbeat(stream pb.PubSub_SubscribeServer, interval time.Duration) *heartbeatStream {
+	ctx, cancel := context.WithCancel(stream.Context())
+	hs := &heartbeatStream{
+		PubSub_SubscribeServer: stream,
+		lastSend:               time.Now(),
+		cancel:                 cancel,
+		done:                   make(chan struct{}),
+	}
+	if interval <= 0 {
+		close(hs.done)
+		return hs
+	}
+	go hs.run(ctx, interval)
+	return hs
+}
+
+// Send 发送消息并记录发送时间
+func (hs *heartbeatStream) Send(resp *pb.SubscribeResponse) error {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if err := hs.PubSub_SubscribeServer.Send(resp); err != nil {
+		return err
+	}
+	hs.lastSend = time.Now()
+	return nil
+}
+
+// run 在距上次发送超过 interval 时发送心跳，发送失败说明客户端已断开
+func (hs *heartbeatStream) run(ctx context.Context, interval time.Duration) {
+	defer close(hs.done)
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		hs.mu.Lock()
+		idle := time.Since(hs.lastSend) >= interval
+		hs.mu.Unlock()
+		if !idle {
+			continue
+		}
+		if err := hs.Send(&pb.SubscribeResponse{Heartbeat: true}); err != nil {
+			log.Printf("发送心跳失败，客户端可能已断开: %v", err)
+			return
+		}
+	}
+}
+
+// stop 停止发送心跳并等待心跳协程退出，处理函数返回后不能再在流上发送
+func (hs *heartbeatStream) stop() {
+	hs.cancel()
+	<-hs.done
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// messageSeq 用于生成非持久化模式下的消息 ID
+var messageSeq atomic.Uint64
+
+// newMessageID 生成服务端消息 ID，格式与 Redis Stream ID 一致
+func newMessageID(now time.Time) string {
+	return fmt.Sprintf("%d-%d", now.UnixMilli(), messageSeq.Add(1))
+}
+
+// newEnvelope 根据发布请求构造消息信封，兼容只设置了 message 字段的旧版客户端
+func newEnvelope(ctx context.Context, req *pb.PublishRequest) *pb.Message {
+	now := time.Now()
+	env := &pb.Message{
+		Id:          newMessageID(now),
+		PublishTime: timestamppb.New(now),
+		Topic:       req.Topic,
+		PublisherId: req.PublisherId,
+		Headers:     req.Headers,
+		ContentType: req.ContentType,
+		Payload:     req.Payload,
+		OrderingKey: req.OrderingKey,
+		Retain:      req.Retain,
+	}
+	if len(env.Payload) == 0 && req.Message != "" {
+		env.Payload = []byte(req.Message)
+		if env.ContentType == "" {
+			env.ContentType = "text/plain"
+		}
+	}
+	if env.PublisherId == "" {
+		if p, ok := peer.FromContext(ctx); ok {
+			env.PublisherId = p.Addr.String()
+		}
+	}
+	return env
+}
+
+// encodeEnvelope 将消息信封编码为 JSON 写入 Redis
+func encodeEnvelope(env *pb.Message) (string, error) {
+	data, err := protojson.Marshal(env)
+	if err != nil {
+		return "", fmt.Errorf("编码消息失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeEnvelope 解析 Redis 中的消息，非信封格式的内容按纯文本消息处理
+func decodeEnvelope(topic, data string) *pb.Message {
+	env := &pb.Message{}
+	if err := protojson.Unmarshal([]byte(data), env); err != nil || env.Topic == "" {
+		return &pb.Message{
+			Topic:       topic,
+			ContentType: "text/plain",
+			Payload:     []byte(data),
+		}
+	}
+	return env
+}
+
+// newSubscribeResponse 构造订阅响应，同时填充旧版 message 字段
+func newSubscribeResponse(env *pb.Message) *pb.SubscribeResponse {
+	return &pb.SubscribeResponse{
+		Message:  string(env.Payload),
+		Id:       env.Id,
+		Envelope: env,
+		Topic:    env.Topic,
+	}
+}
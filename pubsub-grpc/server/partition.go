@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	pb "pubsub/proto/pubsub"
+)
+
+const (
+	membersKeyPrefix   = "pubsub:members:"   // 分区消费者组的成员，有序集合，分数为成员的过期时间（毫秒）
+	partitionKeyPrefix = "pubsub:partition:" // 分区租约键前缀，值为持有分区的成员
+	memberTTL          = 10 * time.Second    // 成员和分区租约的有效期，成员断开后其他成员最迟在此之后接管分区
+	rebalanceInterval  = time.Second         // 刷新成员和重新分配分区的间隔
+	releaseTimeout     = 5 * time.Second     // 退出时释放分区的超时时间
+)
+
+// partitionOf 返回消息所属的分区：有排序键的消息按排序键哈希，相同排序键总在同一分区；
+// 没有排序键的消息随机分散到各分区
+func partitionOf(env *pb.Message, partitions int) int {
+	if env.OrderingKey == "" {
+		return rand.IntN(partitions)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(env.OrderingKey))
+	return int(h.Sum32() % uint32(partitions))
+}
+
+// partitionStream 返回主题分区的 Stream 键 <主题>:p<分区>。
+// 发布时消息同时写入主题 Stream 和所属分区的 Stream，两者的消息 ID 相同
+func partitionStream(topic string, partition int) string {
+	return topic + ":p" + strconv.Itoa(partition)
+}
+
+// isPartitionStream 判断键是否为分区 Stream，即以 :p<分区> 结尾
+func isPartitionStream(key string) bool {
+	i := strings.LastIndex(key, ":p")
+	if i <= 0 || i+2 == len(key) {
+		return false
+	}
+	for _, c := range key[i+2:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// consumerGroup 消费者组在 Redis 中对应的组，启用分区时每个分区的 Stream 上各有一个同名的 Redis 消费者组
+type consumerGroup struct {
+	stream    string // 消费者组所在的 Stream
+	name      string // Redis 消费者组名称
+	partition int    // 分区，未启用分区时为 -1
+}
+
+// consumerGroups 返回消费者组在 Redis 中对应的全部组：未启用分区时为主题 Stream 上的组，
+// 启用分区时为每个分区 Stream 上的组
+func (s *pubSubServer) consumerGroups(topic, group string) []consumerGroup {
+	if s.partitions <= 1 {
+		return []consumerGroup{{stream: topic, name: group, partition: -1}}
+	}
+	groups := make([]consumerGroup, s.partitions)
+	for i := range groups {
+		groups[i] = partitionGroup(topic, group, i)
+	}
+	return groups
+}
+
+// partitionGroup 返回分区 Stream 上的消费者组
+func partitionGroup(topic, group string, partition int) consumerGroup {
+	return consumerGroup{stream: partitionStream(topic, partition), name: group, partition: partition}
+}
+
+// ownedPartition 成员持有的一个分区
+type ownedPartition struct {
+	partition int
+	draining  bool      // 分区已分配给其他成员，等待已投递的消息确认后释放，不再读取新消息
+	since     time.Time // 开始交出分区的时间
+	takeover  bool      // 刚取得的分区，需要立即认领之前持有者未确认的消息
+}
+
+// partitionMember 分区消费者组中的一个订阅连接。
+// 成员定期在 Redis 中登记自己，按名称排序后将分区轮流分配给各成员；
+// 每个分区由租约保护，新成员只有在原持有者释放或租约过期后才能取得分区，保证同一时刻每个分区只投递给一个订阅者
+type partitionMember struct {
+	client     *redis.Client
+	topic      string
+	group      string
+	consumer   string // Redis 消费者名称
+	id         string // 成员 ID，每个订阅连接唯一
+	partitions int
+	drainLimit time.Duration // 交出分区前等待已投递消息确认的最长时间
+
+	mu    sync.Mutex
+	owned map[int]*ownedPartition
+}
+
+// newPartitionMember 创建分区消费者组的成员
+func (s *pubSubServer) newPartitionMember(topic, group, consumer string, drainLimit time.Duration) *partitionMember {
+	return &partitionMember{
+		client:     s.redisClient,
+		topic:      topic,
+		group:      group,
+		consumer:   consumer,
+		id:         newMemberID(consumer),
+		partitions: s.partitions,
+		drainLimit: drainLimit,
+		owned:      make(map[int]*ownedPartition),
+	}
+}
+
+func (m *partitionMember) membersKey() string {
+	return membersKeyPrefix + m.topic + ":" + m.group
+}
+
+func (m *partitionMember) leaseKey(partition int) string {
+	return partitionKeyPrefix + m.topic + ":" + m.group + ":" + strconv.Itoa(partition)
+}
+
+// renewLeaseScript 延长自己持有的租约，返回 0 表示租约已不属于该成员
+var renewLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLeaseScript 删除自己持有的租约
+var releaseLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// assigned 刷新成员登记并返回分配给该成员的分区
+func (m *partitionMember) assigned(ctx context.Context) (map[int]bool, error) {
+	now := time.Now()
+	pipe := m.client.TxPipeline()
+	pipe.ZAdd(ctx, m.membersKey(), redis.Z{Score: float64(now.Add(memberTTL).UnixMilli()), Member: m.id})
+	pipe.ZRemRangeByScore(ctx, m.membersKey(), "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+	pipe.PExpire(ctx, m.membersKey(), 2*memberTTL)
+	members := pipe.ZRange(ctx, m.membersKey(), 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	names := members.Val()
+	slices.Sort(names)
+	assigned := make(map[int]bool)
+	for p := 0; p < m.partitions; p++ {
+		if names[p%len(names)] == m.id {
+			assigned[p] = true
+		}
+	}
+	return assigned, nil
+}
+
+// rebalance 按当前成员重新分配分区：取得新分配的分区，续期仍持有的分区，交出已分配给其他成员的分区
+func (m *partitionMember) rebalance(ctx context.Context) error {
+	assigned, err := m.assigned(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before := m.readable()
+
+	for p := range assigned {
+		if _, ok := m.owned[p]; ok {
+			continue
+		}
+		ok, err := m.client.SetNX(ctx, m.leaseKey(p), m.id, memberTTL).Result()
+		if err != nil {
+			return err
+		}
+		if ok {
+			m.owned[p] = &ownedPartition{partition: p, takeover: true}
+		}
+	}
+
+	for p, op := range m.owned {
+		if assigned[p] != !op.draining {
+			op.draining, op.since = !assigned[p], time.Now()
+		}
+		if op.draining && m.drained(ctx, op) {
+			releaseLeaseScript.Run(ctx, m.client, []string{m.leaseKey(p)}, m.id)
+			delete(m.owned, p)
+			continue
+		}
+		renewed, err := renewLeaseScript.Run(ctx, m.client, []string{m.leaseKey(p)}, m.id, memberTTL.Milliseconds()).Int()
+		if err != nil {
+			return err
+		}
+		if renewed == 0 {
+			// 租约已过期并被其他成员取得
+			log.Printf("消费者 %s 失去了主题 %s 消费者组 %s 的分区 %d", m.consumer, m.topic, m.group, p)
+			delete(m.owned, p)
+		}
+	}
+
+	if after := m.readable(); !slices.Equal(before, after) {
+		log.Printf("消费者 %s 在主题 %s 的消费者组 %s 中分配到分区 %v", m.consumer, m.topic, m.group, after)
+	}
+	return nil
+}
+
+// drained 判断交出中的分区是否可以释放：已投递的消息都已确认，或等待超过了可见性超时
+func (m *partitionMember) drained(ctx context.Context, op *ownedPartition) bool {
+	if time.Since(op.since) > m.drainLimit {
+		return true
+	}
+	pending, err := m.client.XPending(ctx, partitionStream(m.topic, op.partition), m.group).Result()
+	if err != nil {
+		log.Printf("查询分区 %d 的待确认消息失败: %v", op.partition, err)
+		return false
+	}
+	return pending.Consumers[m.consumer] == 0
+}
+
+// readable 返回可以读取新消息的分区，调用方需持有锁
+func (m *partitionMember) readable() []int {
+	var parts []int
+	for p, op := range m.owned {
+		if !op.draining {
+			parts = append(parts, p)
+		}
+	}
+	slices.Sort(parts)
+	return parts
+}
+
+// held 返回成员当前持有的分区，取得后首次返回的分区带有 takeover 标记
+func (m *partitionMember) held() []ownedPartition {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	parts := make([]ownedPartition, 0, len(m.owned))
+	for _, op := range m.owned {
+		parts = append(parts, *op)
+		op.takeover = false
+	}
+	slices.SortFunc(parts, func(a, b ownedPartition) int { return a.partition - b.partition })
+	return parts
+}
+
+// run 定期重新分配分区，直到 ctx 取消
+func (m *partitionMember) run(ctx context.Context) {
+	ticker := time.NewTicker(rebalanceInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.rebalance(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("重新分配主题 %s 消费者组 %s 的分区失败: %v", m.topic, m.group, err)
+			}
+		}
+	}
+}
+
+// leave 退出消费者组并释放持有的分区，其他成员在下一次重新分配时接管
+func (m *partitionMember) leave() {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.client.ZRem(ctx, m.membersKey(), m.id)
+	for p := range m.owned {
+		releaseLeaseScript.Run(ctx, m.client, []string{m.leaseKey(p)}, m.id)
+	}
+	m.owned = make(map[int]*ownedPartition)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// 相同排序键的消息总在同一分区，没有排序键的消息分散到各分区
+func TestPartitionOf(t *testing.T) {
+	const partitions = 4
+	want := partitionOf(&pb.Message{OrderingKey: "order-42"}, partitions)
+	for range 100 {
+		if got := partitionOf(&pb.Message{OrderingKey: "order-42"}, partitions); got != want {
+			t.Fatalf("排序键 order-42 分到分区 %d 和 %d", want, got)
+		}
+	}
+
+	seen := make(map[int]bool)
+	for range 1000 {
+		p := partitionOf(&pb.Message{}, partitions)
+		if p < 0 || p >= partitions {
+			t.Fatalf("分区 %d 超出范围", p)
+		}
+		seen[p] = true
+	}
+	if len(seen) != partitions {
+		t.Errorf("没有排序键的消息只分到了 %d 个分区", len(seen))
+	}
+}
+
+func TestIsPartitionStream(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{partitionStream("orders", 0), true},
+		{partitionStream("orders:eu", 12), true},
+		{"orders", false},
+		{"orders:p", false},
+		{"orders:pX", false},
+		{"orders:p1x", false},
+		{":p1", false},
+		{"orders.p1", false},
+	}
+	for _, tt := range tests {
+		if got := isPartitionStream(tt.key); got != tt.want {
+			t.Errorf("isPartitionStream(%q) = %v，期望 %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+// 启用分区时每个分区 Stream 上有一个同名的消费者组
+func TestConsumerGroups(t *testing.T) {
+	s := &pubSubServer{partitions: 1}
+	groups := s.consumerGroups("orders", "billing")
+	if len(groups) != 1 || groups[0] != (consumerGroup{stream: "orders", name: "billing", partition: -1}) {
+		t.Errorf("未启用分区时的消费者组为 %+v", groups)
+	}
+
+	s.partitions = 3
+	groups = s.consumerGroups("orders", "billing")
+	if len(groups) != 3 {
+		t.Fatalf("消费者组为 %+v，期望 3 个", groups)
+	}
+	for i, g := range groups {
+		want := consumerGroup{stream: "orders:p" + strconv.Itoa(i), name: "billing", partition: i}
+		if g != want {
+			t.Errorf("分区 %d 的消费者组为 %+v，期望 %+v", i, g, want)
+		}
+	}
+}
+
+// 与内部键和分区 Stream 冲突的主题在发布时被拒绝
+func TestPublishReservedTopic(t *testing.T) {
+	s, _ := newTestServer(t, serverConfig{})
+	ctx := context.Background()
+	for _, topic := range []string{"pubsub:dedupe:x", "pubsub:retained", "orders:p1"} {
+		if _, _, err := s.publishMessage(ctx, &pb.PublishRequest{Topic: topic}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("发布到主题 %s 返回 %v，期望 InvalidArgument", topic, err)
+		}
+	}
+	for _, topic := range []string{"orders:eu", "orders:pending", "orders.p1"} {
+		if _, _, err := s.publishMessage(ctx, &pb.PublishRequest{Topic: topic}); err != nil {
+			t.Errorf("发布到主题 %s 失败: %v", topic, err)
+		}
+	}
+}
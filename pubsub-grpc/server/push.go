@@ -0,0 +1,534 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "pubsub/proto/pubsub"
+)
+
+const (
+	pushKey          = "pubsub:push"    // 保存推送订阅的 Hash 键，字段为订阅名称
+	pushGroupPrefix  = "push."          // 持久化模式下推送订阅使用的消费者组名称前缀
+	pushSyncInterval = 10 * time.Second // 重新读取推送订阅、启动和停止投递的间隔
+	pushMinBackoff   = time.Second      // 默认的第一次重试等待时间
+	pushMaxBackoff   = time.Minute      // 默认的重试等待时间上限
+	pushMaxAttempts  = 5                // 推送订阅和服务端都未指定最大投递次数时的最大推送次数
+	pushQueueSize    = 1000             // 非持久化模式下每个推送订阅等待推送的消息上限
+
+	defaultPushTimeout = 10 * time.Second // 每次 HTTP 推送的默认超时时间
+)
+
+// 推送请求的请求头，签名为 HMAC-SHA256(密钥, "<时间戳>.<请求体>") 的十六进制编码
+const (
+	pushHeaderSubscription = "X-Pubsub-Subscription"
+	pushHeaderMessageID    = "X-Pubsub-Message-Id"
+	pushHeaderTopic        = "X-Pubsub-Topic"
+	pushHeaderAttempt      = "X-Pubsub-Delivery-Attempt"
+	pushHeaderTimestamp    = "X-Pubsub-Timestamp"
+	pushHeaderSignature    = "X-Pubsub-Signature"
+)
+
+// headerDeadLetterPush 放弃推送该消息的推送订阅，记录在死信消息头中
+const headerDeadLetterPush = "x-dead-letter-push-subscription"
+
+// pushStore 保存推送订阅的配置
+type pushStore interface {
+	// Create 保存新的推送订阅，同名订阅已存在时返回 false
+	Create(ctx context.Context, sub *pb.PushSubscription) (bool, error)
+	// Delete 删除推送订阅，返回被删除的订阅，订阅不存在时返回 nil
+	Delete(ctx context.Context, name string) (*pb.PushSubscription, error)
+	// List 返回全部推送订阅，按名称排序
+	List(ctx context.Context) ([]*pb.PushSubscription, error)
+}
+
+// newPushStore 选择推送订阅的存储。持久化模式下保存在 Redis 中，服务器重启后恢复，
+// 多个服务器副本通过同一个消费者组分担推送；其他模式下只在本服务器内有效
+func newPushStore(broker Broker, durable bool) pushStore {
+	if rb, ok := broker.(*redisBroker); ok && durable {
+		return &redisPushStore{client: rb.client}
+	}
+	return &memoryPushStore{subs: make(map[string]*pb.PushSubscription)}
+}
+
+// redisPushStore 使用 Redis Hash 保存推送订阅
+type redisPushStore struct {
+	client *redis.Client
+}
+
+func (st *redisPushStore) Create(ctx context.Context, sub *pb.PushSubscription) (bool, error) {
+	data, err := protojson.Marshal(sub)
+	if err != nil {
+		return false, err
+	}
+	return st.client.HSetNX(ctx, pushKey, sub.Name, data).Result()
+}
+
+func (st *redisPushStore) Delete(ctx context.Context, name string) (*pb.PushSubscription, error) {
+	data, err := st.client.HGet(ctx, pushKey, name).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	n, err := st.client.HDel(ctx, pushKey, name).Result()
+	if err != nil || n == 0 {
+		return nil, err
+	}
+	sub := &pb.PushSubscription{}
+	if err := protojson.Unmarshal([]byte(data), sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (st *redisPushStore) List(ctx context.Context) ([]*pb.PushSubscription, error) {
+	values, err := st.client.HGetAll(ctx, pushKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	subs := make([]*pb.PushSubscription, 0, len(values))
+	for name, data := range values {
+		sub := &pb.PushSubscription{}
+		if err := protojson.Unmarshal([]byte(data), sub); err != nil {
+			log.Printf("解析推送订阅 %s 失败: %v", name, err)
+			continue
+		}
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Name < subs[j].Name })
+	return subs, nil
+}
+
+// memoryPushStore 在进程内保存推送订阅，服务器重启后丢失
+type memoryPushStore struct {
+	mu   sync.Mutex
+	subs map[string]*pb.PushSubscription
+}
+
+func (st *memoryPushStore) Create(ctx context.Context, sub *pb.PushSubscription) (bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.subs[sub.Name]; ok {
+		return false, nil
+	}
+	st.subs[sub.Name] = sub
+	return true, nil
+}
+
+func (st *memoryPushStore) Delete(ctx context.Context, name string) (*pb.PushSubscription, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	sub := st.subs[name]
+	delete(st.subs, name)
+	return sub, nil
+}
+
+func (st *memoryPushStore) List(ctx context.Context) ([]*pb.PushSubscription, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	subs := make([]*pb.PushSubscription, 0, len(st.subs))
+	for _, sub := range st.subs {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Name < subs[j].Name })
+	return subs, nil
+}
+
+// pushManager 为每个推送订阅运行一个投递协程，并与存储中的推送订阅保持一致
+type pushManager struct {
+	mu      sync.Mutex
+	running map[string]*runningPush
+}
+
+// runningPush 正在运行的推送订阅
+type runningPush struct {
+	sub    *pb.PushSubscription
+	cancel context.CancelFunc
+}
+
+// runPush 启动已有推送订阅的投递，并定期同步其他服务器副本创建或删除的推送订阅
+func (s *pubSubServer) runPush(ctx context.Context) {
+	ticker := time.NewTicker(pushSyncInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.syncPush(ctx); err != nil {
+			log.Printf("同步推送订阅失败: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncPush 为新的推送订阅启动投递，停止已删除或配置已变化的推送订阅
+func (s *pubSubServer) syncPush(ctx context.Context) error {
+	subs, err := s.pushSubs.List(ctx)
+	if err != nil {
+		return err
+	}
+	m := s.pusher
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := make(map[string]*pb.PushSubscription, len(subs))
+	for _, sub := range subs {
+		current[sub.Name] = sub
+	}
+	for name, r := range m.running {
+		if sub, ok := current[name]; !ok || !proto.Equal(sub, r.sub) {
+			r.cancel()
+			delete(m.running, name)
+		}
+	}
+	for name, sub := range current {
+		if _, ok := m.running[name]; ok {
+			continue
+		}
+		pushCtx, cancel := context.WithCancel(internalContext(ctx))
+		m.running[name] = &runningPush{sub: sub, cancel: cancel}
+		go s.runPushSubscription(pushCtx, sub)
+	}
+	return nil
+}
+
+// runPushSubscription 订阅推送订阅的主题并逐条推送，订阅中断时等待后重新订阅，直到推送订阅被删除。
+// 持久化模式下消费者组中未确认的消息保留在 Stream 中，直接在订阅流中推送；
+// 非持久化模式下订阅与其他订阅者共享上游订阅，消息先放入推送订阅自己的队列，
+// 由单独的协程推送，推送地址缓慢或不可用时不会拖慢同一主题的其他订阅者
+func (s *pubSubServer) runPushSubscription(ctx context.Context, sub *pb.PushSubscription) {
+	log.Printf("开始推送订阅 %s: 主题 %s -> %s", sub.Name, sub.Topic, sub.Endpoint)
+	req := &pb.SubscribeRequest{Topic: sub.Topic, Filter: sub.Filter}
+	if s.durable {
+		req.Group = pushGroupPrefix + sub.Name
+		req.MaxDeliveryAttempts = sub.MaxDeliveryAttempts
+		req.DeadLetterTopic = sub.DeadLetterTopic
+		// 推送一条消息的全部重试完成前，消息不应被其他服务器副本重新认领
+		req.VisibilityTimeoutMs = max(s.visibilityTimeout, s.pushRetryBudget(sub)).Milliseconds()
+	}
+
+	deliver := func(resp *pb.SubscribeResponse) error {
+		return s.pushMessage(ctx, sub, req.Group, resp)
+	}
+	if !s.durable {
+		queue := make(chan *pb.SubscribeResponse, pushQueueSize)
+		go s.runPushQueue(ctx, sub, queue)
+		deliver = func(resp *pb.SubscribeResponse) error {
+			enqueuePush(sub, queue, resp)
+			return nil
+		}
+	}
+	stream := &gatewayStream{ctx: ctx, send: func(resp *pb.SubscribeResponse) error {
+		if resp.Heartbeat || resp.Retained || resp.Envelope == nil {
+			return nil
+		}
+		return deliver(resp)
+	}}
+	retry := pushMinBackoff
+	for {
+		started := time.Now()
+		err := s.Subscribe(req, stream)
+		if ctx.Err() != nil {
+			log.Printf("推送订阅 %s 已停止", sub.Name)
+			return
+		}
+		// 订阅运行过一段时间后才中断的，从最短的等待时间开始重新订阅
+		if time.Since(started) > pushMaxBackoff {
+			retry = pushMinBackoff
+		}
+		log.Printf("推送订阅 %s 的订阅中断，%v 后重新订阅: %v", sub.Name, retry, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry = min(retry*2, pushMaxBackoff)
+	}
+}
+
+// enqueuePush 将消息放入推送队列，不会阻塞。队列已满时丢弃最早的消息，
+// 与非持久化模式下订阅者离线时的消息一样不再推送
+func enqueuePush(sub *pb.PushSubscription, queue chan *pb.SubscribeResponse, resp *pb.SubscribeResponse) {
+	for {
+		select {
+		case queue <- resp:
+			return
+		default:
+		}
+		select {
+		case old := <-queue:
+			log.Printf("推送订阅 %s 的推送队列已满 (%d 条消息)，丢弃消息 %s", sub.Name, pushQueueSize, old.Envelope.Id)
+		default:
+		}
+	}
+}
+
+// runPushQueue 逐条推送队列中的消息，直到推送订阅被删除
+func (s *pubSubServer) runPushQueue(ctx context.Context, sub *pb.PushSubscription, queue <-chan *pb.SubscribeResponse) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case resp := <-queue:
+			if err := s.pushMessage(ctx, sub, "", resp); err != nil && ctx.Err() == nil {
+				log.Printf("推送订阅 %s 推送消息 %s 失败: %v", sub.Name, resp.Envelope.Id, err)
+			}
+		}
+	}
+}
+
+// pushPolicy 返回推送订阅的最大推送次数和重试等待时间，未指定时使用服务端的 -max-delivery-attempts，
+// 服务端也未限制时最多推送 pushMaxAttempts 次
+func (s *pubSubServer) pushPolicy(sub *pb.PushSubscription) (attempts int, minBackoff, maxBackoff time.Duration) {
+	attempts = s.maxDeliveryAttempts
+	if sub.MaxDeliveryAttempts > 0 {
+		attempts = int(sub.MaxDeliveryAttempts)
+	}
+	if attempts <= 0 {
+		attempts = pushMaxAttempts
+	}
+	minBackoff, maxBackoff = pushMinBackoff, pushMaxBackoff
+	if sub.MinBackoffMs > 0 {
+		minBackoff = time.Duration(sub.MinBackoffMs) * time.Millisecond
+	}
+	if sub.MaxBackoffMs > 0 {
+		maxBackoff = time.Duration(sub.MaxBackoffMs) * time.Millisecond
+	}
+	return attempts, minBackoff, max(minBackoff, maxBackoff)
+}
+
+// pushRetryBudget 估算推送一条消息的全部重试所需的最长时间
+func (s *pubSubServer) pushRetryBudget(sub *pb.PushSubscription) time.Duration {
+	attempts, backoff, maxBackoff := s.pushPolicy(sub)
+	total := time.Duration(attempts) * s.pushClient.Timeout
+	for i := 1; i < attempts; i++ {
+		total += backoff
+		backoff = min(backoff*2, maxBackoff)
+	}
+	return total
+}
+
+// pushMessage 推送一条消息，失败时按退避间隔重试，超过最大推送次数后移入死信主题。
+// 只有推送订阅被删除时返回错误，消费者组模式下推送成功或移入死信主题后确认消息
+func (s *pubSubServer) pushMessage(ctx context.Context, sub *pb.PushSubscription, group string, resp *pb.SubscribeResponse) error {
+	env := resp.Envelope
+	body, err := gatewayJSON.Marshal(env)
+	if err != nil {
+		return err
+	}
+	maxAttempts, backoff, maxBackoff := s.pushPolicy(sub)
+
+	for attempt := 1; ; attempt++ {
+		err := s.post(ctx, sub, env, body, attempt)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempt >= maxAttempts {
+			if err := s.deadLetterPush(ctx, sub, env, attempt, err); err != nil {
+				log.Printf("推送订阅 %s 将消息 %s 移入死信主题失败: %v", sub.Name, env.Id, err)
+				return err
+			}
+			break
+		}
+		log.Printf("推送订阅 %s 第 %d 次推送消息 %s 失败，%v 后重试: %v", sub.Name, attempt, env.Id, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+
+	if group != "" {
+		if _, err := s.Ack(ctx, &pb.AckRequest{Topic: resp.Topic, Group: group, Ids: []string{resp.Id}}); err != nil {
+			log.Printf("推送订阅 %s 确认消息 %s 失败: %v", sub.Name, resp.Id, err)
+		}
+	}
+	return nil
+}
+
+// post 将 JSON 格式的消息信封 POST 到推送地址，返回 2xx 以外的状态码视为失败
+func (s *pubSubServer) post(ctx context.Context, sub *pb.PushSubscription, env *pb.Message, body []byte, attempt int) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(pushHeaderSubscription, sub.Name)
+	req.Header.Set(pushHeaderMessageID, env.Id)
+	req.Header.Set(pushHeaderTopic, env.Topic)
+	req.Header.Set(pushHeaderAttempt, strconv.Itoa(attempt))
+	req.Header.Set(pushHeaderTimestamp, timestamp)
+	req.Header.Set(pushHeaderSignature, "sha256="+signPush(sub.Secret, timestamp, body))
+
+	resp, err := s.pushClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("推送地址返回 %s", resp.Status)
+	}
+	return nil
+}
+
+// signPush 计算推送请求的签名，接收方用同一个密钥重新计算并比较，同时校验时间戳防止重放
+func signPush(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// deadLetterPush 将推送失败的消息发布到死信主题，消息头记录原主题、推送次数和最后一次失败原因
+func (s *pubSubServer) deadLetterPush(ctx context.Context, sub *pb.PushSubscription, env *pb.Message, attempts int, lastErr error) error {
+	deadLetterTopic := sub.DeadLetterTopic
+	if deadLetterTopic == "" {
+		deadLetterTopic = sub.Topic + s.deadLetterSuffix
+	}
+	dead := proto.Clone(env).(*pb.Message)
+	dead.Topic = deadLetterTopic
+	dead.PublishTime = timestamppb.Now()
+	dead.Retain = false
+	if dead.Headers == nil {
+		dead.Headers = make(map[string]string)
+	}
+	dead.Headers[headerOriginalTopic] = env.Topic
+	dead.Headers[headerOriginalID] = env.Id
+	dead.Headers[headerOriginalTime] = env.PublishTime.AsTime().Format(time.RFC3339Nano)
+	dead.Headers[headerDeliveryAttempts] = strconv.Itoa(attempts)
+	dead.Headers[headerLastError] = lastErr.Error()
+	dead.Headers[headerDeadLetterPush] = sub.Name
+	dead.Headers[headerDeadLetterTime] = dead.PublishTime.AsTime().Format(time.RFC3339Nano)
+
+	if err := s.publishNow(ctx, dead); err != nil {
+		return err
+	}
+	log.Printf("推送订阅 %s 推送消息 %s 失败 %d 次，移入死信主题 %s (消息 %s)",
+		sub.Name, env.Id, attempts, deadLetterTopic, dead.Id)
+	return nil
+}
+
+// CreatePushSubscription 创建推送订阅，需要管理权限。返回的订阅包含签名密钥，之后不再返回
+func (s *pubSubServer) CreatePushSubscription(ctx context.Context, req *pb.CreatePushSubscriptionRequest) (*pb.PushSubscription, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if req.Subscription == nil {
+		return nil, status.Error(codes.InvalidArgument, "必须指定推送订阅")
+	}
+	sub := proto.Clone(req.Subscription).(*pb.PushSubscription)
+	if sub.Topic == "" || isPattern(sub.Topic) {
+		return nil, status.Error(codes.InvalidArgument, "推送订阅必须指定一个主题")
+	}
+	if err := validateTopics(sub.Topic, sub.DeadLetterTopic); err != nil {
+		return nil, err
+	}
+	if u, err := url.Parse(sub.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "无效的推送地址: %q", sub.Endpoint)
+	}
+	if _, err := parseFilter(sub.Filter); err != nil {
+		return nil, err
+	}
+	if sub.MaxDeliveryAttempts < 0 || sub.MinBackoffMs < 0 || sub.MaxBackoffMs < 0 {
+		return nil, status.Error(codes.InvalidArgument, "推送次数和重试等待时间不能为负数")
+	}
+	if sub.Name == "" {
+		sub.Name = "push-" + rand.Text()
+	}
+	if sub.Secret == "" {
+		sub.Secret = rand.Text()
+	}
+
+	created, err := s.pushSubs.Create(ctx, sub)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "保存推送订阅失败: %v", err)
+	}
+	if !created {
+		return nil, status.Errorf(codes.AlreadyExists, "推送订阅 %s 已存在", sub.Name)
+	}
+	if err := s.syncPush(context.Background()); err != nil {
+		log.Printf("同步推送订阅失败: %v", err)
+	}
+	log.Printf("已创建推送订阅 %s: 主题 %s -> %s", sub.Name, sub.Topic, sub.Endpoint)
+	return sub, nil
+}
+
+// DeletePushSubscription 删除推送订阅并停止推送，需要管理权限
+func (s *pubSubServer) DeletePushSubscription(ctx context.Context, req *pb.DeletePushSubscriptionRequest) (*pb.DeletePushSubscriptionResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "必须指定推送订阅名称")
+	}
+	sub, err := s.pushSubs.Delete(ctx, req.Name)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "删除推送订阅失败: %v", err)
+	}
+	if err := s.syncPush(context.Background()); err != nil {
+		log.Printf("同步推送订阅失败: %v", err)
+	}
+	if sub == nil {
+		return &pb.DeletePushSubscriptionResponse{}, nil
+	}
+	// 持久化模式下一并删除推送订阅的消费者组，未推送的消息不再保留
+	if s.durable {
+		for _, g := range s.consumerGroups(sub.Topic, pushGroupPrefix+sub.Name) {
+			if err := s.redisClient.XGroupDestroy(ctx, g.stream, g.name).Err(); err != nil {
+				log.Printf("删除推送订阅 %s 的消费者组 %s 失败: %v", sub.Name, g.name, err)
+			}
+		}
+		s.redisClient.HDel(ctx, groupFiltersKeyPrefix+sub.Topic, pushGroupPrefix+sub.Name)
+	}
+	log.Printf("已删除推送订阅 %s", req.Name)
+	return &pb.DeletePushSubscriptionResponse{Deleted: true}, nil
+}
+
+// ListPushSubscriptions 列出推送订阅，不返回签名密钥，需要管理权限
+func (s *pubSubServer) ListPushSubscriptions(ctx context.Context, req *pb.ListPushSubscriptionsRequest) (*pb.ListPushSubscriptionsResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	subs, err := s.pushSubs.List(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "读取推送订阅失败: %v", err)
+	}
+	resp := &pb.ListPushSubscriptionsResponse{}
+	for _, sub := range subs {
+		sub = proto.Clone(sub).(*pb.PushSubscription)
+		sub.Secret = ""
+		resp.Subscriptions = append(resp.Subscriptions, sub)
+	}
+	return resp, nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// pushAttempt 推送地址收到的一次推送
+type pushAttempt struct {
+	header http.Header
+	body   []byte
+}
+
+// pushEndpoint 记录收到的推送，按 statuses 依次返回状态码，之后返回最后一个状态码
+type pushEndpoint struct {
+	mu       sync.Mutex
+	statuses []int
+	attempts []pushAttempt
+	received chan struct{}
+}
+
+func newPushEndpoint(t *testing.T, statuses ...int) (*pushEndpoint, string) {
+	e := &pushEndpoint{statuses: statuses, received: make(chan struct{}, 100)}
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+	return e, srv.URL
+}
+
+func (e *pushEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	e.mu.Lock()
+	n := len(e.attempts)
+	e.attempts = append(e.attempts, pushAttempt{header: r.Header.Clone(), body: body})
+	code := e.statuses[min(n, len(e.statuses)-1)]
+	e.mu.Unlock()
+	w.WriteHeader(code)
+	e.received <- struct{}{}
+}
+
+// wait 等待推送地址累计收到 n 次推送
+func (e *pushEndpoint) wait(t *testing.T, n int) []pushAttempt {
+	t.Helper()
+	for {
+		e.mu.Lock()
+		attempts := append([]pushAttempt(nil), e.attempts...)
+		e.mu.Unlock()
+		if len(attempts) >= n {
+			return attempts
+		}
+		select {
+		case <-e.received:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("推送地址收到 %d 次推送，期望 %d 次", len(attempts), n)
+		}
+	}
+}
+
+// createPush 创建推送订阅，等待它订阅主题后返回，测试结束时删除
+func createPush(t *testing.T, s *pubSubServer, broker *memoryBroker, sub *pb.PushSubscription) *pb.PushSubscription {
+	t.Helper()
+	created, err := s.CreatePushSubscription(context.Background(), &pb.CreatePushSubscriptionRequest{Subscription: sub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		s.DeletePushSubscription(context.Background(), &pb.DeletePushSubscriptionRequest{Name: created.Name})
+	})
+	waitSubscribers(t, broker, sub.Topic, 1)
+	return created
+}
+
+// 推送请求带有可校验的签名，推送地址返回非 2xx 时重试
+func TestPushSignatureAndRetry(t *testing.T) {
+	s, broker := newTestServer(t, serverConfig{})
+	endpoint, url := newPushEndpoint(t, http.StatusServiceUnavailable, http.StatusOK)
+	sub := createPush(t, s, broker, &pb.PushSubscription{
+		Name: "billing", Topic: "orders", Endpoint: url, Secret: "s3cret", MinBackoffMs: 1,
+	})
+
+	if _, _, err := s.publishMessage(context.Background(), &pb.PublishRequest{Topic: "orders", Payload: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	attempts := endpoint.wait(t, 2)
+	for i, a := range attempts {
+		if got := a.header.Get(pushHeaderAttempt); got != []string{"1", "2"}[i] {
+			t.Errorf("第 %d 次推送的 %s 为 %q", i+1, pushHeaderAttempt, got)
+		}
+		if got := a.header.Get(pushHeaderSubscription); got != sub.Name {
+			t.Errorf("%s 为 %q，期望 %q", pushHeaderSubscription, got, sub.Name)
+		}
+		want := "sha256=" + signPush("s3cret", a.header.Get(pushHeaderTimestamp), a.body)
+		if got := a.header.Get(pushHeaderSignature); !hmac.Equal([]byte(got), []byte(want)) {
+			t.Errorf("签名为 %q，期望 %q", got, want)
+		}
+		if !strings.Contains(string(a.body), `"topic":"orders"`) {
+			t.Errorf("推送的消息信封 %s 不包含主题", a.body)
+		}
+	}
+
+	// 推送成功后不再重试
+	select {
+	case <-endpoint.received:
+		t.Fatal("推送成功后不应再次推送")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// 超过最大推送次数的消息移入死信主题
+func TestPushDeadLetter(t *testing.T) {
+	s, broker := newTestServer(t, serverConfig{DeadLetterSuffix: ".dlq"})
+	endpoint, url := newPushEndpoint(t, http.StatusInternalServerError)
+	createPush(t, s, broker, &pb.PushSubscription{
+		Name: "billing", Topic: "orders", Endpoint: url, MaxDeliveryAttempts: 3, MinBackoffMs: 1,
+	})
+	dlq, err := broker.Subscribe(context.Background(), "orders.dlq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dlq.Close()
+
+	env, _, err := s.publishMessage(context.Background(), &pb.PublishRequest{Topic: "orders", Payload: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := receive(t, dlq).Message
+	if n := len(endpoint.wait(t, 3)); n != 3 {
+		t.Errorf("推送了 %d 次，期望 3 次", n)
+	}
+	if dead.Headers[headerOriginalID] != env.Id || dead.Headers[headerOriginalTopic] != "orders" {
+		t.Errorf("死信消息头 %v 没有记录原消息", dead.Headers)
+	}
+	if dead.Headers[headerDeliveryAttempts] != "3" || dead.Headers[headerDeadLetterPush] != "billing" {
+		t.Errorf("死信消息头 %v 没有记录推送次数和推送订阅", dead.Headers)
+	}
+}
+
+// 推送地址阻塞时，共享上游订阅的其他订阅者照常收到消息，推送地址恢复后排队的消息依次推送
+func TestPushSlowEndpoint(t *testing.T) {
+	inner := newMemoryBroker(Retention{})
+	broker := newFanoutBroker(inner)
+	defer broker.Close()
+	s, err := NewPubSubServer(broker, serverConfig{BufferSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	pushed := make(chan struct{}, 100)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		pushed <- struct{}{}
+	}))
+	defer slow.Close()
+	created, err := s.CreatePushSubscription(context.Background(), &pb.CreatePushSubscriptionRequest{
+		Subscription: &pb.PushSubscription{Name: "slow", Topic: "orders", Endpoint: slow.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.DeletePushSubscription(context.Background(), &pb.DeletePushSubscriptionRequest{Name: created.Name})
+	waitSubscribers(t, inner, "orders", 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan struct{}, 1)
+	startSubscribe(ctx, s, &pb.SubscribeRequest{Topic: "orders"}, func(resp *pb.SubscribeResponse) error {
+		if resp.Envelope != nil {
+			received <- struct{}{}
+		}
+		return nil
+	})
+	waitFanoutMembers(t, broker, "orders", 2)
+
+	const total = 50
+	for i := range total {
+		if _, _, err := s.publishMessage(ctx, &pb.PublishRequest{Topic: "orders", Payload: []byte("x")}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatalf("推送地址阻塞时其他订阅者没有收到第 %d 条消息", i+1)
+		}
+	}
+
+	close(release)
+	for i := range total {
+		select {
+		case <-pushed:
+		case <-time.After(time.Second):
+			t.Fatalf("推送地址恢复后只推送了 %d 条消息，期望 %d 条", i, total)
+		}
+	}
+}
+
+// waitFanoutMembers 等待主题的上游订阅有 n 个本地订阅者
+func waitFanoutMembers(t *testing.T, b *fanoutBroker, topic string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		b.mu.Lock()
+		refs := 0
+		if up, ok := b.upstreams[topic]; ok {
+			refs = up.refs
+		}
+		b.mu.Unlock()
+		if refs == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("主题 %s 的本地订阅者为 %d 个，期望 %d 个", topic, refs, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
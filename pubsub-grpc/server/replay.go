@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// Retention 主题历史消息的保留策略
+type Retention struct {
+	MaxMessages int           // 每个主题最多保留的消息数，0 表示不保留历史
+	MaxAge      time.Duration // 消息最长保留时间，0 表示不限制
+}
+
+// Replayer 支持回放历史消息的消息代理
+type Replayer interface {
+	// Replay 按起始位置返回主题（可以是 glob 模式）的历史消息，按发布顺序排列
+	Replay(ctx context.Context, topics []string, start startPosition) ([]*pb.Message, error)
+}
+
+// startPosition 订阅的起始位置
+type startPosition struct {
+	kind    pb.StartPosition
+	afterID string    // START_AFTER_ID：回放该 ID 之后的消息
+	since   time.Time // START_FROM_TIME：回放该时间及之后的消息
+	lastN   int       // START_LAST_N：回放最近 N 条消息
+}
+
+// parseStartPosition 校验并解析 SubscribeRequest 中的起始位置
+func parseStartPosition(req *pb.SubscribeRequest) (startPosition, error) {
+	start := startPosition{kind: req.StartPosition}
+	switch req.StartPosition {
+	case pb.StartPosition_START_LATEST, pb.StartPosition_START_EARLIEST:
+	case pb.StartPosition_START_AFTER_ID:
+		if _, _, ok := parseMessageID(req.StartId); !ok {
+			return start, status.Errorf(codes.InvalidArgument, "无效的起始消息 ID: %q", req.StartId)
+		}
+		start.afterID = req.StartId
+	case pb.StartPosition_START_FROM_TIME:
+		if req.StartTime == nil {
+			return start, status.Error(codes.InvalidArgument, "START_FROM_TIME 需要指定 start_time")
+		}
+		start.since = req.StartTime.AsTime()
+	case pb.StartPosition_START_LAST_N:
+		if req.LastN <= 0 {
+			return start, status.Error(codes.InvalidArgument, "START_LAST_N 需要指定正数 last_n")
+		}
+		start.lastN = int(req.LastN)
+	default:
+		return start, status.Errorf(codes.InvalidArgument, "未知的起始位置: %v", req.StartPosition)
+	}
+	return start, nil
+}
+
+// latest 是否只接收新消息
+func (p startPosition) latest() bool {
+	return p.kind == pb.StartPosition_START_LATEST
+}
+
+// parseMessageID 解析 "毫秒时间戳-序号" 格式的消息 ID
+func parseMessageID(id string) (ms, seq uint64, ok bool) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, false
+	}
+	ms, err1 := strconv.ParseUint(msPart, 10, 64)
+	seq, err2 := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq, err1 == nil && err2 == nil
+}
+
+// compareMessageID 比较两个消息 ID 的先后，无法解析的 ID 按字符串比较
+func compareMessageID(a, b string) int {
+	ams, aseq, aok := parseMessageID(a)
+	bms, bseq, bok := parseMessageID(b)
+	if !aok || !bok {
+		return strings.Compare(a, b)
+	}
+	switch {
+	case ams != bms:
+		if ams < bms {
+			return -1
+		}
+		return 1
+	case aseq != bseq:
+		if aseq < bseq {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// selectHistory 从按发布顺序排列的历史消息中选出起始位置之后、未过期的消息
+func selectHistory(history []*pb.Message, start startPosition, maxAge time.Duration) []*pb.Message {
+	var cutoff time.Time
+	if maxAge > 0 {
+		cutoff = time.Now().Add(-maxAge)
+	}
+
+	var selected []*pb.Message
+	for _, m := range history {
+		t := m.PublishTime.AsTime()
+		if !cutoff.IsZero() && t.Before(cutoff) {
+			continue
+		}
+		switch start.kind {
+		case pb.StartPosition_START_AFTER_ID:
+			if compareMessageID(m.Id, start.afterID) <= 0 {
+				continue
+			}
+		case pb.StartPosition_START_FROM_TIME:
+			if t.Before(start.since) {
+				continue
+			}
+		}
+		selected = append(selected, m)
+	}
+
+	return trimLastN(selected, start)
+}
+
+// trimLastN 对 START_LAST_N 只保留最后 N 条消息
+func trimLastN(msgs []*pb.Message, start startPosition) []*pb.Message {
+	if start.kind == pb.StartPosition_START_LAST_N && len(msgs) > start.lastN {
+		return msgs[len(msgs)-start.lastN:]
+	}
+	return msgs
+}
+
+// sortMessages 按发布时间和消息 ID 排序，用于合并多个主题的历史消息
+func sortMessages(msgs []*pb.Message) {
+	sort.SliceStable(msgs, func(i, j int) bool {
+		ti, tj := msgs[i].PublishTime.AsTime(), msgs[j].PublishTime.AsTime()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return compareMessageID(msgs[i].Id, msgs[j].Id) < 0
+	})
+}
+
+// matchedPattern 返回订阅中匹配该主题的模式，主题被精确订阅时返回空字符串
+func matchedPattern(topics []string, topic string) string {
+	pattern := ""
+	for _, t := range topics {
+		if t == topic {
+			return ""
+		}
+		if pattern == "" && isPattern(t) && matchTopic(t, topic) {
+			pattern = t
+		}
+	}
+	return pattern
+}
+
+// replayKey 用于去重回放消息和实时消息
+func replayKey(m *pb.Message) string {
+	return fmt.Sprintf("%s/%s", m.Topic, m.Id)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// replyTopicPrefix 请求的回复主题前缀，回复主题由服务端为每个请求生成
+const replyTopicPrefix = "_reply."
+
+// defaultRequestTimeout 请求和服务器都没有指定超时时等待响应的时间
+const defaultRequestTimeout = 10 * time.Second
+
+// 请求消息的消息头，响应者将响应发布到回复主题，并原样带回关联 ID
+const (
+	headerReplyTo       = "x-reply-to"       // 回复主题
+	headerCorrelationID = "x-correlation-id" // 关联 ID
+)
+
+// isReplyTopic 判断是否为请求的回复主题。回复主题是临时主题：
+// 只有发出请求的服务器在等待，消息不写入 Stream 和历史
+func isReplyTopic(topic string) bool {
+	return strings.HasPrefix(topic, replyTopicPrefix)
+}
+
+// newReplyTopic 为发往 topic 的请求生成回复主题 _reply.<请求主题>.<随机串>，
+// 随机串只出现在请求消息中，回复主题中的请求主题用于校验响应者的发布权限
+func newReplyTopic(topic string) string {
+	return replyTopicPrefix + topic + "." + rand.Text()
+}
+
+// replyRequestTopic 返回回复主题对应的请求主题，不是由 Request 生成的回复主题返回 false
+func replyRequestTopic(topic string) (string, bool) {
+	rest, ok := strings.CutPrefix(topic, replyTopicPrefix)
+	if !ok {
+		return "", false
+	}
+	i := strings.LastIndexByte(rest, '.')
+	if i <= 0 || i == len(rest)-1 {
+		return "", false
+	}
+	return rest[:i], true
+}
+
+// Request 发布请求消息并等待第一条关联 ID 相同的响应，超时返回 DEADLINE_EXCEEDED
+func (s *pubSubServer) Request(ctx context.Context, req *pb.RequestRequest) (*pb.RequestResponse, error) {
+	if req.Topic == "" || isPattern(req.Topic) {
+		return nil, status.Error(codes.InvalidArgument, "必须指定一个请求主题")
+	}
+	if isReplyTopic(req.Topic) {
+		return nil, status.Error(codes.InvalidArgument, "不能向回复主题发送请求")
+	}
+	if err := validateTopics(req.Topic); err != nil {
+		return nil, err
+	}
+	if req.TimeoutMs < 0 {
+		return nil, status.Error(codes.InvalidArgument, "timeout_ms 不能为负数")
+	}
+	timeout := s.requestTimeout
+	if req.TimeoutMs > 0 {
+		timeout = time.Duration(req.TimeoutMs) * time.Millisecond
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// 先订阅回复主题再发布请求，避免错过响应
+	replyTopic := newReplyTopic(req.Topic)
+	correlationID := rand.Text()
+	sub, err := s.broker.Subscribe(waitCtx, replyTopic)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "订阅回复主题失败: %v", err)
+	}
+	defer sub.Close()
+
+	headers := make(map[string]string, len(req.Headers)+2)
+	for k, v := range req.Headers {
+		headers[k] = v
+	}
+	headers[headerReplyTo] = replyTopic
+	headers[headerCorrelationID] = correlationID
+	env, _, err := s.publishMessage(ctx, &pb.PublishRequest{
+		Topic:       req.Topic,
+		PublisherId: req.PublisherId,
+		Headers:     headers,
+		ContentType: req.ContentType,
+		Payload:     req.Payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case d, ok := <-sub.Messages():
+			if !ok {
+				return nil, status.Error(codes.Unavailable, "回复主题的订阅已关闭")
+			}
+			if d.Message.Headers[headerCorrelationID] != correlationID {
+				continue
+			}
+			return &pb.RequestResponse{Reply: d.Message, RequestId: env.Id, CorrelationId: correlationID}, nil
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return nil, status.FromContextError(ctx.Err()).Err()
+			}
+			log.Printf("请求 %s (主题 %s) 在 %v 内没有收到响应", env.Id, req.Topic, timeout)
+			return nil, status.Errorf(codes.DeadlineExceeded, "等待主题 %s 的响应超时 (%v)", req.Topic, timeout)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+const (
+	retainedKey     = "pubsub:retained"      // 保存各主题保留消息的 Hash 键，字段为主题，值为消息信封
+	retainedTimeKey = "pubsub:retained:time" // 保存各主题保留消息发布时间的 Hash 键，值为定长的纳秒时间戳
+)
+
+// retainStore 保存每个主题最新的保留消息
+type retainStore interface {
+	// Retain 替换主题的保留消息，已有的保留消息比 env 更新时不替换
+	Retain(ctx context.Context, env *pb.Message) error
+	// Retained 返回主题或匹配模式的主题的保留消息，按主题排序
+	Retained(ctx context.Context, topics []string) ([]*pb.Message, error)
+	// Clear 删除主题的保留消息，返回主题是否有保留消息
+	Clear(ctx context.Context, topic string) (bool, error)
+}
+
+// newRetainStore 根据消息代理选择保留消息的存储，Redis 消息代理使用 Redis，多个服务器副本共享保留消息
+func newRetainStore(broker Broker) retainStore {
+	if rb, ok := broker.(*redisBroker); ok {
+		return &redisRetainStore{client: rb.client}
+	}
+	return &memoryRetainStore{messages: make(map[string]*pb.Message)}
+}
+
+// matchRetained 筛选出主题匹配订阅主题或模式的保留消息，按主题排序
+func matchRetained(messages []*pb.Message, topics []string) []*pb.Message {
+	var matched []*pb.Message
+	for _, env := range messages {
+		for _, t := range topics {
+			if t == env.Topic || isPattern(t) && matchTopic(t, env.Topic) {
+				matched = append(matched, env)
+				break
+			}
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Topic < matched[j].Topic })
+	return matched
+}
+
+// retainTime 返回比较保留消息新旧的时间：延迟消息为计划投递时间，其他消息为发布时间
+func retainTime(env *pb.Message) time.Time {
+	if env.DeliverAt != nil {
+		return env.DeliverAt.AsTime()
+	}
+	return env.PublishTime.AsTime()
+}
+
+// newerRetained 判断 env 是否不早于已有的保留消息 current
+func newerRetained(env, current *pb.Message) bool {
+	return current == nil || !retainTime(env).Before(retainTime(current))
+}
+
+// redisRetainStore 使用 Redis Hash 保存保留消息
+type redisRetainStore struct {
+	client *redis.Client
+}
+
+// retainScript 只在消息不早于已有的保留消息时替换，并发发布的保留消息以最新的为准。
+// 时间戳为定长字符串，按字符串比较即按时间比较
+var retainScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[2], ARGV[1])
+if current and current > ARGV[2] then
+  return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+return 1
+`)
+
+func (st *redisRetainStore) Retain(ctx context.Context, env *pb.Message) error {
+	data, err := encodeEnvelope(env)
+	if err != nil {
+		return err
+	}
+	at := fmt.Sprintf("%020d", retainTime(env).UnixNano())
+	return retainScript.Run(ctx, st.client, []string{retainedKey, retainedTimeKey}, env.Topic, at, data).Err()
+}
+
+// Retained 只订阅确定的主题时按主题读取，包含模式时读取全部保留消息后筛选
+func (st *redisRetainStore) Retained(ctx context.Context, topics []string) ([]*pb.Message, error) {
+	var values map[string]string
+	if slices.ContainsFunc(topics, isPattern) {
+		all, err := st.client.HGetAll(ctx, retainedKey).Result()
+		if err != nil {
+			return nil, err
+		}
+		values = all
+	} else {
+		found, err := st.client.HMGet(ctx, retainedKey, topics...).Result()
+		if err != nil {
+			return nil, err
+		}
+		values = make(map[string]string)
+		for i, v := range found {
+			if data, ok := v.(string); ok {
+				values[topics[i]] = data
+			}
+		}
+	}
+
+	messages := make([]*pb.Message, 0, len(values))
+	for topic, data := range values {
+		messages = append(messages, decodeEnvelope(topic, data))
+	}
+	return matchRetained(messages, topics), nil
+}
+
+func (st *redisRetainStore) Clear(ctx context.Context, topic string) (bool, error) {
+	pipe := st.client.TxPipeline()
+	n := pipe.HDel(ctx, retainedKey, topic)
+	pipe.HDel(ctx, retainedTimeKey, topic)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return n.Val() > 0, nil
+}
+
+// memoryRetainStore 在进程内保存保留消息，服务器重启后丢失
+type memoryRetainStore struct {
+	mu       sync.Mutex
+	messages map[string]*pb.Message
+}
+
+func (st *memoryRetainStore) Retain(ctx context.Context, env *pb.Message) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if newerRetained(env, st.messages[env.Topic]) {
+		st.messages[env.Topic] = env
+	}
+	return nil
+}
+
+func (st *memoryRetainStore) Retained(ctx context.Context, topics []string) ([]*pb.Message, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	messages := make([]*pb.Message, 0, len(st.messages))
+	for _, env := range st.messages {
+		messages = append(messages, env)
+	}
+	return matchRetained(messages, topics), nil
+}
+
+func (st *memoryRetainStore) Clear(ctx context.Context, topic string) (bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	_, ok := st.messages[topic]
+	delete(st.messages, topic)
+	return ok, nil
+}
+
+// retain 将发布成功的保留消息保存为主题的保留消息，保存失败不影响发布结果
+func (s *pubSubServer) retain(ctx context.Context, env *pb.Message) {
+	if !env.Retain {
+		return
+	}
+	if err := s.retained.Retain(ctx, env); err != nil {
+		log.Printf("保存主题 %s 的保留消息 %s 失败: %v", env.Topic, env.Id, err)
+	}
+}
+
+// sendRetained 向新的订阅者发送订阅主题的保留消息，返回已发送的消息，之后收到的相同实时消息应跳过。
+// 没有权限或不满足过滤条件的保留消息不发送
+func (s *pubSubServer) sendRetained(ctx context.Context, topics []string, filter *messageFilter, id string, stream pb.PubSub_SubscribeServer) (map[string]bool, error) {
+	messages, err := s.retained.Retained(ctx, topics)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "读取保留消息失败: %v", err)
+	}
+	sent := make(map[string]bool)
+	for _, env := range messages {
+		if !filter.match(env) || !s.permitted(ctx, env.Topic) {
+			continue
+		}
+		resp := newSubscribeResponse(env)
+		resp.Pattern = matchedPattern(topics, env.Topic)
+		resp.SubscriptionId = id
+		resp.Retained = true
+		if err := stream.Send(resp); err != nil {
+			return nil, status.Errorf(codes.Internal, "发送保留消息失败: %v", err)
+		}
+		sent[replayKey(env)] = true
+	}
+	return sent, nil
+}
+
+// ClearRetained 删除主题的保留消息，需要主题的发布权限
+func (s *pubSubServer) ClearRetained(ctx context.Context, req *pb.ClearRetainedRequest) (*pb.ClearRetainedResponse, error) {
+	if req.Topic == "" || isPattern(req.Topic) {
+		return nil, status.Error(codes.InvalidArgument, "必须指定一个主题")
+	}
+	if err := s.authorize(ctx, permPublish, req.Topic); err != nil {
+		return nil, err
+	}
+	cleared, err := s.retained.Clear(ctx, req.Topic)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "清除保留消息失败: %v", err)
+	}
+	if cleared {
+		log.Printf("已清除主题 %s 的保留消息", req.Topic)
+	}
+	return &pb.ClearRetainedResponse{Cleared: cleared}, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// retainedAt 构造一条在 at 发布的保留消息
+func retainedAt(id string, at time.Time) *pb.Message {
+	return &pb.Message{Id: id, Topic: "status", Retain: true, PublishTime: timestamppb.New(at)}
+}
+
+// 较早发布的保留消息后保存时不替换较新的保留消息
+func TestMemoryRetainNewestWins(t *testing.T) {
+	ctx := context.Background()
+	st := &memoryRetainStore{messages: make(map[string]*pb.Message)}
+	now := time.Now()
+
+	for _, env := range []*pb.Message{
+		retainedAt("2", now),
+		retainedAt("1", now.Add(-time.Second)),
+	} {
+		if err := st.Retain(ctx, env); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, _ := st.Retained(ctx, []string{"status"})
+	if len(got) != 1 || got[0].Id != "2" {
+		t.Fatalf("保留消息为 %v，期望较新的消息 2", got)
+	}
+
+	// 延迟消息按计划投递时间比较
+	delayed := retainedAt("3", now.Add(-time.Minute))
+	delayed.DeliverAt = timestamppb.New(now.Add(time.Second))
+	st.Retain(ctx, delayed)
+	if got, _ := st.Retained(ctx, []string{"status"}); got[0].Id != "3" {
+		t.Errorf("保留消息为 %s，期望到期发布的延迟消息 3", got[0].Id)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "pubsub/proto/pubsub"
+)
+
+const (
+	scheduledKey        = "pubsub:scheduled"         // 等待投递的延迟消息，分数为计划投递时间（毫秒）
+	scheduledClaimedKey = "pubsub:scheduled:claimed" // 已被取出等待发布的延迟消息，分数为租约到期时间（毫秒）
+	scheduleBatch       = 100                        // 每次取出的最大延迟消息数
+	scheduleLease       = 30 * time.Second           // 取出的延迟消息未在租约内发布时重新放回等待队列
+	headerScheduledID   = "x-scheduled-id"           // 延迟消息发布时记录发布确认中的消息 ID
+)
+
+// scheduleStore 保存延迟消息，到期的消息由调度循环取出后发布
+type scheduleStore interface {
+	// Schedule 保存消息，在 env.DeliverAt 之后可以被取出
+	Schedule(ctx context.Context, env *pb.Message) error
+	// Claim 取出最多 limit 条已到期的消息，同一条消息只会被一个调度者取出
+	Claim(ctx context.Context, now time.Time, limit int) ([]scheduledMessage, error)
+	// Done 标记取出的消息已发布
+	Done(ctx context.Context, msg scheduledMessage) error
+}
+
+// scheduledMessage 取出的延迟消息
+type scheduledMessage struct {
+	env *pb.Message
+	key string // 消息在存储中的键，用于标记已发布
+}
+
+// newScheduleStore 根据消息代理选择延迟消息的存储，Redis 消息代理使用 Redis 有序集合
+func newScheduleStore(broker Broker) scheduleStore {
+	if rb, ok := broker.(*redisBroker); ok {
+		return &redisScheduleStore{client: rb.client}
+	}
+	return &memoryScheduleStore{}
+}
+
+// deliverAt 解析发布请求的计划投递时间，立即投递时返回零值
+func deliverAt(req *pb.PublishRequest, now time.Time) (time.Time, error) {
+	switch {
+	case req.DeliverAt != nil && req.DelayMs != 0:
+		return time.Time{}, status.Error(codes.InvalidArgument, "deliver_at 和 delay_ms 只能指定一个")
+	case req.DelayMs < 0:
+		return time.Time{}, status.Error(codes.InvalidArgument, "delay_ms 不能为负数")
+	case req.DelayMs > 0:
+		return now.Add(time.Duration(req.DelayMs) * time.Millisecond), nil
+	case req.DeliverAt != nil:
+		if err := req.DeliverAt.CheckValid(); err != nil {
+			return time.Time{}, status.Errorf(codes.InvalidArgument, "无效的 deliver_at: %v", err)
+		}
+		if at := req.DeliverAt.AsTime(); at.After(now) {
+			return at, nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+// runScheduler 定期取出到期的延迟消息并发布到主题，直到 ctx 取消。
+// 多个服务器副本共享同一个 Redis 时，每条消息只会被其中一个副本发布
+func (s *pubSubServer) runScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			due, err := s.scheduler.Claim(ctx, time.Now(), scheduleBatch)
+			if err != nil {
+				log.Printf("读取延迟消息失败: %v", err)
+				break
+			}
+			for _, msg := range due {
+				s.releaseScheduled(ctx, msg)
+			}
+			if len(due) < scheduleBatch {
+				break
+			}
+		}
+	}
+}
+
+// releaseScheduled 发布一条到期的延迟消息，发布失败的消息在租约到期后重新取出并重试。
+// 发布时分配新的消息 ID，消息头 x-scheduled-id 记录发布确认中的 ID，供发布者对应两者。
+// 延迟消息至少投递一次：发布成功但标记已发布失败时，消息会在租约到期后再次发布
+func (s *pubSubServer) releaseScheduled(ctx context.Context, msg scheduledMessage) {
+	env := proto.Clone(msg.env).(*pb.Message)
+	if env.Headers == nil {
+		env.Headers = make(map[string]string)
+	}
+	env.Headers[headerScheduledID] = msg.env.Id
+	if err := s.publishNow(ctx, env); err != nil {
+		log.Printf("发布延迟消息 %s 到主题 %s 失败，%v 后重试: %v", msg.env.Id, env.Topic, scheduleLease, err)
+		return
+	}
+	if err := s.scheduler.Done(ctx, msg); err != nil {
+		log.Printf("标记延迟消息 %s 已发布失败，%v 后可能再次发布: %v", msg.env.Id, scheduleLease, err)
+	}
+	log.Printf("已发布延迟消息 %s 到主题 %s (消息 %s)", msg.env.Id, env.Topic, env.Id)
+}
+
+// claimScript 原子地取出到期的延迟消息并转入租约集合，先把租约到期的消息放回等待队列
+var claimScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, m in ipairs(expired) do
+	redis.call('ZREM', KEYS[2], m)
+	redis.call('ZADD', KEYS[1], ARGV[1], m)
+end
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+for _, m in ipairs(due) do
+	redis.call('ZREM', KEYS[1], m)
+	redis.call('ZADD', KEYS[2], ARGV[2], m)
+end
+return due
+`)
+
+// redisScheduleStore 使用 Redis 有序集合保存延迟消息，服务器重启后仍会投递
+type redisScheduleStore struct {
+	client *redis.Client
+}
+
+// Schedule 以计划投递时间为分数保存消息
+func (st *redisScheduleStore) Schedule(ctx context.Context, env *pb.Message) error {
+	data, err := encodeEnvelope(env)
+	if err != nil {
+		return err
+	}
+	score := float64(env.DeliverAt.AsTime().UnixMilli())
+	return st.client.ZAdd(ctx, scheduledKey, redis.Z{Score: score, Member: data}).Err()
+}
+
+// Claim 取出到期的消息，取出的消息在租约内未标记发布时会被重新取出
+func (st *redisScheduleStore) Claim(ctx context.Context, now time.Time, limit int) ([]scheduledMessage, error) {
+	keys := []string{scheduledKey, scheduledClaimedKey}
+	lease := now.Add(scheduleLease).UnixMilli()
+	members, err := claimScript.Run(ctx, st.client, keys, now.UnixMilli(), lease, limit).StringSlice()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	msgs := make([]scheduledMessage, 0, len(members))
+	for _, m := range members {
+		env := decodeEnvelope("", m)
+		if env.Topic == "" {
+			log.Printf("丢弃无法解析的延迟消息: %s", strconv.Quote(m))
+			st.client.ZRem(ctx, scheduledClaimedKey, m)
+			continue
+		}
+		msgs = append(msgs, scheduledMessage{env: env, key: m})
+	}
+	return msgs, nil
+}
+
+// Done 从租约集合中删除已发布的消息
+func (st *redisScheduleStore) Done(ctx context.Context, msg scheduledMessage) error {
+	return st.client.ZRem(ctx, scheduledClaimedKey, msg.key).Err()
+}
+
+// memoryScheduleStore 在进程内保存延迟消息，服务器重启后丢失。
+// 与 Redis 存储一样，取出的消息在租约内未标记发布时重新放回等待队列
+type memoryScheduleStore struct {
+	mu      sync.Mutex
+	pending []*pb.Message           // 按计划投递时间排序
+	claimed map[string]claimedEntry // 已被取出等待发布的消息，按键索引
+	nextKey uint64
+}
+
+// claimedEntry 已被取出的延迟消息及其租约到期时间
+type claimedEntry struct {
+	env   *pb.Message
+	lease time.Time
+}
+
+// Schedule 按计划投递时间插入消息
+func (st *memoryScheduleStore) Schedule(ctx context.Context, env *pb.Message) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.insert(proto.Clone(env).(*pb.Message))
+	return nil
+}
+
+// insert 按计划投递时间将消息插入等待队列，调用方持有 st.mu
+func (st *memoryScheduleStore) insert(env *pb.Message) {
+	at := env.DeliverAt.AsTime()
+	i := sort.Search(len(st.pending), func(i int) bool {
+		return st.pending[i].DeliverAt.AsTime().After(at)
+	})
+	st.pending = append(st.pending, nil)
+	copy(st.pending[i+1:], st.pending[i:])
+	st.pending[i] = env
+}
+
+// Claim 取出到期的消息并转入租约，先把租约到期的消息放回等待队列
+func (st *memoryScheduleStore) Claim(ctx context.Context, now time.Time, limit int) ([]scheduledMessage, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for key, c := range st.claimed {
+		if !c.lease.After(now) {
+			delete(st.claimed, key)
+			st.insert(c.env)
+		}
+	}
+
+	n := 0
+	for n < len(st.pending) && n < limit && !st.pending[n].DeliverAt.AsTime().After(now) {
+		n++
+	}
+	if st.claimed == nil {
+		st.claimed = make(map[string]claimedEntry)
+	}
+	due := make([]scheduledMessage, n)
+	for i, env := range st.pending[:n] {
+		st.nextKey++
+		key := strconv.FormatUint(st.nextKey, 10)
+		st.claimed[key] = claimedEntry{env: env, lease: now.Add(scheduleLease)}
+		due[i] = scheduledMessage{env: env, key: key}
+	}
+	st.pending = slices.Delete(st.pending, 0, n)
+	return due, nil
+}
+
+// Done 删除已发布消息的租约
+func (st *memoryScheduleStore) Done(ctx context.Context, msg scheduledMessage) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.claimed, msg.key)
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// scheduledAt 构造一条计划在 at 投递的消息
+func scheduledAt(topic string, at time.Time) *pb.Message {
+	env := testMessage(topic, "delayed")
+	env.DeliverAt = timestamppb.New(at)
+	return env
+}
+
+// 取出后未标记发布的消息在租约到期后重新取出，标记发布后不再取出
+func TestMemoryScheduleStoreLease(t *testing.T) {
+	ctx := context.Background()
+	st := &memoryScheduleStore{}
+	now := time.Now()
+	if err := st.Schedule(ctx, scheduledAt("a", now.Add(time.Second))); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Schedule(ctx, scheduledAt("b", now.Add(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+
+	if due, _ := st.Claim(ctx, now, scheduleBatch); len(due) != 0 {
+		t.Fatalf("未到期时取出了 %d 条消息", len(due))
+	}
+	now = now.Add(2 * time.Second)
+	due, _ := st.Claim(ctx, now, scheduleBatch)
+	if len(due) != 1 || due[0].env.Topic != "a" {
+		t.Fatalf("取出 %d 条消息，期望主题 a 的 1 条", len(due))
+	}
+	if again, _ := st.Claim(ctx, now.Add(scheduleLease/2), scheduleBatch); len(again) != 0 {
+		t.Fatalf("租约内重复取出了 %d 条消息", len(again))
+	}
+
+	// 租约到期后重新取出
+	now = now.Add(scheduleLease)
+	again, _ := st.Claim(ctx, now, scheduleBatch)
+	if len(again) != 1 || again[0].env.Id != due[0].env.Id {
+		t.Fatalf("租约到期后取出 %d 条消息，期望重新取出 %s", len(again), due[0].env.Id)
+	}
+	if err := st.Done(ctx, again[0]); err != nil {
+		t.Fatal(err)
+	}
+	if left, _ := st.Claim(ctx, now.Add(2*scheduleLease), scheduleBatch); len(left) != 0 {
+		t.Fatalf("标记发布后又取出了 %d 条消息", len(left))
+	}
+}
+
+// 发布失败的延迟消息不会丢失，租约到期后重试
+func TestReleaseScheduledRetry(t *testing.T) {
+	ctx := context.Background()
+	broker := newMemoryBroker(Retention{})
+	store := &memoryScheduleStore{}
+	s, err := NewPubSubServer(broker, serverConfig{BufferSize: 1, Scheduler: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if err := store.Schedule(ctx, scheduledAt("orders", now)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 消息代理关闭后发布失败
+	broker.Close()
+	due, _ := store.Claim(ctx, now, scheduleBatch)
+	if len(due) != 1 {
+		t.Fatalf("取出 %d 条消息，期望 1 条", len(due))
+	}
+	s.releaseScheduled(ctx, due[0])
+
+	retry, _ := store.Claim(ctx, now.Add(scheduleLease), scheduleBatch)
+	if len(retry) != 1 || retry[0].env.Id != due[0].env.Id {
+		t.Fatalf("发布失败的消息在租约到期后取出 %d 条，期望重新取出", len(retry))
+	}
+}
+
+// 发布的延迟消息在消息头中记录发布确认中的消息 ID
+func TestReleaseScheduledID(t *testing.T) {
+	ctx := context.Background()
+	broker := newMemoryBroker(Retention{})
+	defer broker.Close()
+	store := &memoryScheduleStore{}
+	s, err := NewPubSubServer(broker, serverConfig{BufferSize: 1, Scheduler: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := broker.Subscribe(ctx, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	env, _, err := s.publishMessage(ctx, &pb.PublishRequest{Topic: "orders", DelayMs: 1, Payload: []byte("x")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, _ := store.Claim(ctx, time.Now().Add(time.Second), scheduleBatch)
+	if len(due) != 1 {
+		t.Fatalf("取出 %d 条消息，期望 1 条", len(due))
+	}
+	s.releaseScheduled(ctx, due[0])
+
+	got := receive(t, sub).Message
+	if got.Headers[headerScheduledID] != env.Id {
+		t.Errorf("发布的消息头为 %v，期望 %s 记录延迟消息 %s", got.Headers, headerScheduledID, env.Id)
+	}
+}
@@ -2,36 +2,143 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "pubsub/proto/pubsub"
 )
 
 type pubSubServer struct {
 	pb.UnimplementedPubSubServer
-	redisClient *redis.Client
+	broker      Broker
+	redisClient *redis.Client // 持久化模式使用的 Redis 客户端
+	retention   Retention     // 持久化模式下主题 Stream 的保留范围
+
+	durable           bool          // 持久化模式：主题使用 Redis Stream 存储
+	visibilityTimeout time.Duration // 未确认消息重新投递的默认超时时间
+
+	maxDeliveryAttempts int    // 消费者组中消息的默认最大投递次数，0 表示不限制
+	deadLetterSuffix    string // 默认死信主题的后缀
+	partitions          int    // 每个主题的分区数，大于 1 时消费者组按分区分配消息
+
+	bufferSize     int               // 每个订阅者的默认缓冲区大小
+	overflowPolicy pb.OverflowPolicy // 缓冲区满时的默认溢出策略
+
+	heartbeatInterval time.Duration // 订阅流空闲时发送心跳的默认间隔
+
+	subMu         sync.Mutex
+	subscriptions map[string]*registeredSubscription // 按订阅 ID 登记的非持久化订阅
+	durableSubs   map[string]int                     // 持久化模式下每个主题的活跃订阅者数量
+
+	stats     *topicStats    // 本服务器的主题发布统计
+	scheduler scheduleStore  // 延迟消息的存储
+	retained  retainStore    // 每个主题的保留消息
+	acl       *accessControl // 主题访问控制，为 nil 时不校验
+
+	dedupe       dedupeStore   // 幂等发布记录
+	dedupeWindow time.Duration // 幂等发布的去重窗口，0 表示不去重
+
+	requestTimeout time.Duration // 请求未指定超时时等待响应的时间
+
+	pushSubs   pushStore    // 推送订阅的配置
+	pusher     *pushManager // 本服务器上运行的推送订阅
+	pushClient *http.Client // 推送消息使用的 HTTP 客户端
 }
 
 var _ pb.PubSubServer = (*pubSubServer)(nil)
 
-func NewPubSubServer() *pubSubServer {
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: "localhost:6379",
-	})
-	_, err := redisClient.Ping(context.Background()).Result()
-	if err != nil {
-		log.Fatalf("failed to connect to Redis: %v", err)
+// serverConfig PubSub 服务的配置
+type serverConfig struct {
+	Durable             bool
+	VisibilityTimeout   time.Duration
+	MaxDeliveryAttempts int
+	DeadLetterSuffix    string
+	Partitions          int
+	BufferSize          int
+	OverflowPolicy      pb.OverflowPolicy
+	HeartbeatInterval   time.Duration
+	Scheduler           scheduleStore
+	Retained            retainStore
+	ACL                 *accessControl
+	Dedupe              dedupeStore
+	DedupeWindow        time.Duration
+	RequestTimeout      time.Duration
+	PushStore           pushStore
+	PushTimeout         time.Duration
+}
+
+// NewPubSubServer 创建 PubSub 服务，持久化模式需要使用 Redis 消息代理
+func NewPubSubServer(broker Broker, cfg serverConfig) (*pubSubServer, error) {
+	if cfg.Scheduler == nil {
+		cfg.Scheduler = &memoryScheduleStore{}
+	}
+	if cfg.Retained == nil {
+		cfg.Retained = &memoryRetainStore{messages: make(map[string]*pb.Message)}
+	}
+	if cfg.Dedupe == nil {
+		cfg.Dedupe = &memoryDedupeStore{entries: make(map[string]dedupeEntry)}
+	}
+	if cfg.PushStore == nil {
+		cfg.PushStore = &memoryPushStore{subs: make(map[string]*pb.PushSubscription)}
+	}
+	if cfg.PushTimeout <= 0 {
+		cfg.PushTimeout = defaultPushTimeout
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = defaultRequestTimeout
+	}
+	if cfg.BufferSize <= 0 {
+		return nil, errors.New("订阅者缓冲区大小必须为正数")
+	}
+	if cfg.Partitions > 1 && !cfg.Durable {
+		return nil, errors.New("分区需要使用持久化模式")
 	}
-	return &pubSubServer{
-		redisClient: redisClient,
+	s := &pubSubServer{
+		broker:              broker,
+		durable:             cfg.Durable,
+		visibilityTimeout:   cfg.VisibilityTimeout,
+		maxDeliveryAttempts: cfg.MaxDeliveryAttempts,
+		deadLetterSuffix:    cfg.DeadLetterSuffix,
+		partitions:          cfg.Partitions,
+		bufferSize:          cfg.BufferSize,
+		overflowPolicy:      cfg.OverflowPolicy,
+		heartbeatInterval:   cfg.HeartbeatInterval,
+		subscriptions:       make(map[string]*registeredSubscription),
+		durableSubs:         make(map[string]int),
+		stats:               newTopicStats(),
+		scheduler:           cfg.Scheduler,
+		retained:            cfg.Retained,
+		acl:                 cfg.ACL,
+		dedupe:              cfg.Dedupe,
+		dedupeWindow:        cfg.DedupeWindow,
+		requestTimeout:      cfg.RequestTimeout,
+		pushSubs:            cfg.PushStore,
+		pusher:              &pushManager{running: make(map[string]*runningPush)},
+		pushClient:          &http.Client{Timeout: cfg.PushTimeout},
 	}
+	if cfg.Durable {
+		rb, ok := broker.(*redisBroker)
+		if !ok {
+			return nil, errors.New("持久化模式需要使用 Redis 消息代理")
+		}
+		s.redisClient = rb.client
+		s.retention = rb.retention
+	}
+	return s, nil
 }
 
 func (s *pubSubServer) Publish(stream pb.PubSub_PublishServer) error {
@@ -51,45 +158,444 @@ func (s *pubSubServer) Publish(stream pb.PubSub_PublishServer) error {
 			return status.Errorf(codes.Internal, "接收消息失败: %v", err)
 		}
 
-		// 发布消息到Redis
-		err = s.redisClient.Publish(ctx, req.Topic, req.Message).Err()
+		env, duplicate, err := s.publishMessage(ctx, req)
 		if err != nil {
-			return status.Errorf(codes.Internal, "发布消息失败: %v", err)
+			return err
+		}
+		if duplicate {
+			log.Printf("忽略重复的消息 (主题 %s, 首次发布的消息 %s)", env.Topic, env.Id)
+			continue
 		}
-		
+
 		messageCount++
-		log.Printf("已发布消息到主题 %s: %s", req.Topic, req.Message)
+		log.Printf("已发布消息 %s 到主题 %s (发布者 %s): %s", env.Id, env.Topic, env.PublisherId, env.Payload)
 	}
 }
 
+// PublishStream 逐条发布消息并返回确认，单条消息发布失败不会中断流
+func (s *pubSubServer) PublishStream(stream pb.PubSub_PublishStreamServer) error {
+	ctx := stream.Context()
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "接收消息失败: %v", err)
+		}
+
+		ack := &pb.PublishAck{Sequence: req.Sequence}
+		env, duplicate, err := s.publishMessage(ctx, req)
+		if err != nil {
+			st := status.Convert(err)
+			ack.Code = int32(st.Code())
+			ack.Error = st.Message()
+			log.Printf("发布消息失败 (序列号 %d): %v", req.Sequence, err)
+		} else if duplicate {
+			ack.MessageId = env.Id
+			ack.Duplicate = true
+			log.Printf("忽略重复的消息 (主题 %s, 序列号 %d, 首次发布的消息 %s)", env.Topic, req.Sequence, env.Id)
+		} else if env.DeliverAt != nil {
+			ack.MessageId = env.Id
+			ack.Scheduled = true
+			log.Printf("已保存延迟消息 %s 到主题 %s (发布者 %s, 序列号 %d, 投递时间 %s)", env.Id, env.Topic, env.PublisherId, req.Sequence, env.DeliverAt.AsTime().Format(time.RFC3339))
+		} else {
+			ack.MessageId = env.Id
+			log.Printf("已发布消息 %s 到主题 %s (发布者 %s, 序列号 %d): %s", env.Id, env.Topic, env.PublisherId, req.Sequence, env.Payload)
+		}
+
+		if err := stream.Send(ack); err != nil {
+			return status.Errorf(codes.Internal, "发送确认失败: %v", err)
+		}
+	}
+}
+
+// publishMessage 构造消息信封并发布到 Redis，返回的错误为 gRPC 状态错误。
+// 去重窗口内重复的幂等消息不再发布，返回的信封 ID 为首次发布的消息 ID，duplicate 为 true；
+// 首次发布仍在进行时返回 ABORTED，首次发布可能失败，重试不能当作重复确认
+func (s *pubSubServer) publishMessage(ctx context.Context, req *pb.PublishRequest) (env *pb.Message, duplicate bool, err error) {
+	if req.Topic == "" {
+		return nil, false, status.Error(codes.InvalidArgument, "主题不能为空")
+	}
+	if err := validateTopics(req.Topic); err != nil {
+		return nil, false, err
+	}
+	if err := s.authorize(ctx, permPublish, req.Topic); err != nil {
+		return nil, false, err
+	}
+
+	env = newEnvelope(ctx, req)
+	at, err := deliverAt(req, env.PublishTime.AsTime())
+	if err != nil {
+		return nil, false, err
+	}
+	key, err := dedupeKey(req)
+	if err != nil {
+		return nil, false, err
+	}
+	if s.dedupeWindow <= 0 {
+		key = ""
+	}
+
+	if key != "" {
+		id, dup, err := s.dedupe.Reserve(ctx, key)
+		if err != nil {
+			return nil, false, status.Errorf(codes.Unavailable, "检查重复消息失败: %v", err)
+		}
+		if dup && id == "" {
+			return nil, false, status.Error(codes.Aborted, "相同幂等键的消息正在发布，请稍后重试")
+		}
+		if dup {
+			env.Id = id
+			return env, true, nil
+		}
+	}
+
+	if err := s.deliver(ctx, env, at); err != nil {
+		if key != "" {
+			if err := s.dedupe.Release(ctx, key); err != nil {
+				log.Printf("释放幂等键失败: %v", err)
+			}
+		}
+		return nil, false, err
+	}
+	if key != "" {
+		if err := s.dedupe.Commit(ctx, key, env.Id, s.dedupeWindow); err != nil {
+			log.Printf("记录幂等键失败: %v", err)
+		}
+	}
+	return env, false, nil
+}
+
+// deliver 发布消息，指定了计划投递时间的消息先保存到延迟消息存储
+func (s *pubSubServer) deliver(ctx context.Context, env *pb.Message, at time.Time) error {
+	if !at.IsZero() {
+		env.DeliverAt = timestamppb.New(at)
+		if err := s.scheduler.Schedule(ctx, env); err != nil {
+			return status.Errorf(codes.Unavailable, "保存延迟消息失败: %v", err)
+		}
+		return nil
+	}
+
+	if err := s.publishNow(ctx, env); err != nil {
+		return status.Errorf(codes.Unavailable, "发布消息失败: %v", err)
+	}
+	return nil
+}
+
+// publishNow 立即发布消息并记录统计，保留消息同时替换主题的保留消息。
+// 回复主题的消息只通过消息代理转发给等待响应的服务器，不持久化也不计入统计
+func (s *pubSubServer) publishNow(ctx context.Context, env *pb.Message) error {
+	if isReplyTopic(env.Topic) {
+		env.Retain = false
+		var err error
+		env.Id, err = s.broker.Publish(ctx, env)
+		return err
+	}
+
+	var err error
+	if s.durable {
+		env.Id, err = s.publishDurable(ctx, env)
+	} else {
+		env.Id, err = s.broker.Publish(ctx, env)
+	}
+	if err != nil {
+		return err
+	}
+	s.stats.record(env.Topic, time.Now())
+	s.retain(ctx, env)
+	return nil
+}
+
+// internalKeyPrefix 服务端内部 Redis 键的前缀，主题不能使用
+const internalKeyPrefix = "pubsub:"
+
+// validateTopics 拒绝与 Redis 中的内部键冲突的主题。持久化模式下主题名直接作为 Stream 键：
+// pubsub: 开头的主题会访问服务端的内部数据，以 :p<分区> 结尾的主题与其他主题的分区 Stream 冲突。
+// 空主题由调用方处理
+func validateTopics(topics ...string) error {
+	for _, t := range topics {
+		switch {
+		case strings.HasPrefix(t, internalKeyPrefix):
+			return status.Errorf(codes.InvalidArgument, "主题 %s 不能以保留前缀 %s 开头", t, internalKeyPrefix)
+		case isPartitionStream(t):
+			return status.Errorf(codes.InvalidArgument, "主题 %s 不能以分区后缀 :p<分区> 结尾", t)
+		}
+	}
+	return nil
+}
+
 func (s *pubSubServer) Subscribe(req *pb.SubscribeRequest, stream pb.PubSub_SubscribeServer) error {
-	pubsub := s.redisClient.Subscribe(stream.Context(), req.Topic)
-	defer pubsub.Close()
+	topics := requestTopics(req)
+	if len(topics) == 0 {
+		return status.Error(codes.InvalidArgument, "必须指定至少一个主题")
+	}
+	if err := validateTopics(append(topics, req.DeadLetterTopic)...); err != nil {
+		return err
+	}
+	if err := s.authorize(stream.Context(), permSubscribe, topics...); err != nil {
+		return err
+	}
+	start, err := parseStartPosition(req)
+	if err != nil {
+		return err
+	}
+	filter, err := parseFilter(req.Filter)
+	if err != nil {
+		return err
+	}
+
+	hs := startHeartbeat(stream, s.subscriberHeartbeat(req))
+	defer hs.stop()
+	stream = hs
 
-	log.Printf("客户端已订阅主题: %s", req.Topic)
+	if s.durable {
+		if len(topics) > 1 || isPattern(topics[0]) {
+			return status.Error(codes.InvalidArgument, "持久化模式下只支持订阅单个主题")
+		}
+		req.Topic = topics[0]
+		return s.subscribeDurable(req, start, filter, stream)
+	}
+	if req.Group != "" {
+		return status.Error(codes.FailedPrecondition, "消费者组仅在持久化模式下可用")
+	}
 
-	ch := pubsub.Channel()
-	for msg := range ch {
-		err := stream.Send(&pb.SubscribeResponse{Message: msg.Payload})
+	ctx := stream.Context()
+	id := req.SubscriptionId
+	if id == "" {
+		id = newSubscriptionID()
+	}
+	size, policy := s.subscriberBufferSize(req), s.subscriberOverflowPolicy(req)
+	sub, err := s.broker.Subscribe(withSubscriberBuffer(ctx, size, policy), topics...)
+	if err != nil {
+		return status.Errorf(codes.Internal, "订阅失败: %v", err)
+	}
+	defer sub.Close()
+	if err := s.registerSubscription(ctx, id, sub); err != nil {
+		return err
+	}
+	defer s.unregisterSubscription(id)
+
+	if filter != nil {
+		log.Printf("客户端已订阅主题: %v (订阅 %s, 过滤条件 %s)", topics, id, filter.expr)
+	} else {
+		log.Printf("客户端已订阅主题: %v (订阅 %s)", topics, id)
+	}
+
+	// 先建立实时订阅再回放历史，避免两者之间的消息丢失；回放过的消息在实时消息中跳过
+	replayed, err := s.replay(ctx, topics, start, filter, id, stream)
+	if err != nil {
+		return err
+	}
+	// 从最新消息开始的订阅先收到订阅主题当前的保留消息
+	if start.latest() {
+		if replayed, err = s.sendRetained(ctx, topics, filter, id, stream); err != nil {
+			return err
+		}
+	}
+
+	// 实时消息经过有界缓冲区转发，订阅者处理过慢时按溢出策略处理。
+	// 服务端分发直接把消息放入订阅的缓冲区，其他消息代理由这里读取订阅的消息通道
+	var buf *subscriberBuffer
+	if b, ok := sub.(Buffered); ok {
+		buf = b.Buffer()
+	} else {
+		buf = newSubscriberBuffer(size, policy)
+		go buf.fill(ctx, sub)
+	}
+
+	var lastDropped uint64
+	for {
+		// pop 在客户端取消时立即返回，即使订阅的主题长时间没有消息
+		d, dropped, err := buf.pop(ctx)
 		if err != nil {
+			if ctx.Err() != nil {
+				log.Printf("订阅 %s 已取消", id)
+				return status.FromContextError(ctx.Err()).Err()
+			}
+			log.Printf("订阅 %s 处理过慢，已断开", id)
+			return err
+		}
+		if d == nil {
+			return nil
+		}
+		if dropped > lastDropped {
+			log.Printf("订阅 %s 缓冲区已满，累计丢弃 %d 条消息", id, dropped)
+			lastDropped = dropped
+		}
+
+		env := d.Message
+		// 模式订阅中没有权限的主题和 ACL 重新加载后被收回权限的主题不再发送
+		if replayed[replayKey(env)] || !filter.match(env) || !s.permitted(ctx, env.Topic) {
+			continue
+		}
+		resp := newSubscribeResponse(env)
+		resp.Pattern = d.Pattern
+		resp.SubscriptionId = id
+		resp.DroppedCount = dropped
+		if err := stream.Send(resp); err != nil {
 			return status.Errorf(codes.Internal, "发送消息失败: %v", err)
 		}
-		log.Printf("已发送消息到订阅者 (主题 %s): %s", req.Topic, msg.Payload)
+		log.Printf("已发送消息 %s 到订阅者 (主题 %s): %s", env.Id, env.Topic, env.Payload)
 	}
-	return nil
+}
+
+// subscriberBufferSize 返回订阅者的缓冲区大小，请求未指定时使用服务默认值
+func (s *pubSubServer) subscriberBufferSize(req *pb.SubscribeRequest) int {
+	if req.BufferSize > 0 {
+		return int(req.BufferSize)
+	}
+	return s.bufferSize
+}
+
+// subscriberOverflowPolicy 返回订阅者的溢出策略，请求未指定时使用服务默认值
+func (s *pubSubServer) subscriberOverflowPolicy(req *pb.SubscribeRequest) pb.OverflowPolicy {
+	if req.OverflowPolicy != pb.OverflowPolicy_OVERFLOW_DEFAULT {
+		return req.OverflowPolicy
+	}
+	return s.overflowPolicy
+}
+
+// subscriberHeartbeat 返回订阅流的心跳间隔，请求未指定时使用服务端默认值
+func (s *pubSubServer) subscriberHeartbeat(req *pb.SubscribeRequest) time.Duration {
+	if req.HeartbeatIntervalMs > 0 {
+		return time.Duration(req.HeartbeatIntervalMs) * time.Millisecond
+	}
+	return s.heartbeatInterval
+}
+
+// replay 按起始位置向订阅者发送满足过滤条件的历史消息，返回已发送消息的去重键
+func (s *pubSubServer) replay(ctx context.Context, topics []string, start startPosition, filter *messageFilter, id string, stream pb.PubSub_SubscribeServer) (map[string]bool, error) {
+	if start.latest() {
+		return nil, nil
+	}
+	replayer, ok := s.broker.(Replayer)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "当前消息代理不支持回放历史消息")
+	}
+
+	history, err := replayer.Replay(ctx, topics, start)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "回放历史消息失败: %v", err)
+	}
+
+	replayed := make(map[string]bool, len(history))
+	for _, env := range history {
+		if !filter.match(env) || !s.permitted(ctx, env.Topic) {
+			continue
+		}
+		resp := newSubscribeResponse(env)
+		resp.Pattern = matchedPattern(topics, env.Topic)
+		resp.SubscriptionId = id
+		if err := stream.Send(resp); err != nil {
+			return nil, status.Errorf(codes.Internal, "发送消息失败: %v", err)
+		}
+		replayed[replayKey(env)] = true
+	}
+	log.Printf("已向订阅 %s 回放 %d 条历史消息", id, len(replayed))
+	return replayed, nil
 }
 
 func main() {
-	lis, err := net.Listen("tcp", ":1234")
+	port := flag.Int("port", 1234, "服务端口")
+	brokerName := flag.String("broker", "redis", "消息代理: redis 或 memory")
+	redisAddr := flag.String("redis-addr", "localhost:6379", "Redis 地址")
+	durable := flag.Bool("durable", false, "持久化模式：使用 Redis Stream 存储主题，支持消费者组和消息确认")
+	visibilityTimeout := flag.Duration("visibility-timeout", 30*time.Second, "未确认消息重新投递的默认超时时间")
+	maxDeliveryAttempts := flag.Int("max-delivery-attempts", 0, "消费者组中每条消息的最大投递次数，超过后移入死信主题，0 表示不限制")
+	deadLetterSuffix := flag.String("dead-letter-suffix", ".dlq", "默认死信主题的后缀，死信主题为 <主题><后缀>")
+	partitions := flag.Int("partitions", 1, "每个主题的分区数 (仅持久化模式)，相同排序键的消息属于同一分区，消费者组中每个分区同一时刻只投递给一个订阅者")
+	retentionCount := flag.Int("retention", 1000, "每个主题保留的历史消息数，用于订阅时回放，0 表示不保留；持久化模式下为主题 Stream 的最大长度，0 表示不限制")
+	retentionAge := flag.Duration("retention-age", 24*time.Hour, "历史消息的最长保留时间，持久化模式下同样用于裁剪主题 Stream，0 表示不限制")
+	bufferSize := flag.Int("subscriber-buffer", 100, "每个订阅者的消息缓冲区大小")
+	overflow := flag.String("overflow-policy", "block", "订阅者缓冲区满时的处理策略: block, drop-oldest, drop-newest 或 disconnect")
+	fanout := flag.Bool("fanout", true, "同一主题的订阅者共享一个上游订阅，由服务端分发消息 (持久化模式下不使用)")
+	scheduleInterval := flag.Duration("schedule-interval", time.Second, "检查到期延迟消息的间隔")
+	heartbeat := flag.Duration("heartbeat", 15*time.Second, "订阅流空闲时发送心跳的间隔，0 表示不发送")
+	dedupeWindow := flag.Duration("dedupe-window", 10*time.Minute, "幂等发布的去重窗口，窗口内相同生产者序列号或幂等键的消息只发布一次，0 表示不去重")
+	requestTimeout := flag.Duration("request-timeout", defaultRequestTimeout, "请求未指定 timeout_ms 时等待响应的时间")
+	httpPort := flag.Int("http-port", 0, "HTTP 网关端口，提供 Server-Sent Events、WebSocket 订阅和 HTTP 发布，0 表示不启用")
+	httpOrigins := flag.String("http-allowed-origins", "", "允许建立 WebSocket 连接的页面来源，逗号分隔 (如 https://dash.example.com)，* 表示允许所有来源，为空时只允许同源页面")
+	pushTimeout := flag.Duration("push-timeout", defaultPushTimeout, "推送订阅每次 HTTP 推送的超时时间")
+	aclPath := flag.String("acl", "", "ACL 文件路径，指定后调用方必须携带访问令牌，只能发布和订阅 ACL 允许的主题，收到 SIGHUP 时重新加载")
+	flag.Parse()
+
+	overflowPolicy, err := parseOverflowPolicy(*overflow)
+	if err != nil {
+		log.Fatalf("解析参数失败: %v", err)
+	}
+
+	var acl *accessControl
+	if *aclPath != "" {
+		if acl, err = newAccessControl(*aclPath); err != nil {
+			log.Fatalf("加载 ACL 失败: %v", err)
+		}
+		go reloadOnHangup(acl)
+	}
+
+	retention := Retention{MaxMessages: *retentionCount, MaxAge: *retentionAge}
+	broker, err := newBroker(*brokerName, *redisAddr, retention)
+	if err != nil {
+		log.Fatalf("创建消息代理失败: %v", err)
+	}
+	scheduler := newScheduleStore(broker)
+	dedupe := newDedupeStore(broker)
+	retained := newRetainStore(broker)
+	pushSubs := newPushStore(broker, *durable)
+	if *fanout && !*durable {
+		broker = newFanoutBroker(broker)
+	}
+	defer broker.Close()
+
+	pubSub, err := NewPubSubServer(broker, serverConfig{
+		Durable:             *durable,
+		VisibilityTimeout:   *visibilityTimeout,
+		MaxDeliveryAttempts: *maxDeliveryAttempts,
+		DeadLetterSuffix:    *deadLetterSuffix,
+		Partitions:          *partitions,
+		BufferSize:          *bufferSize,
+		OverflowPolicy:      overflowPolicy,
+		HeartbeatInterval:   *heartbeat,
+		Scheduler:           scheduler,
+		Retained:            retained,
+		ACL:                 acl,
+		Dedupe:              dedupe,
+		DedupeWindow:        *dedupeWindow,
+		RequestTimeout:      *requestTimeout,
+		PushStore:           pushSubs,
+		PushTimeout:         *pushTimeout,
+	})
+	if err != nil {
+		log.Fatalf("创建服务失败: %v", err)
+	}
+
+	go pubSub.runScheduler(context.Background(), *scheduleInterval)
+	go pubSub.runPush(context.Background())
+	if *httpPort != 0 {
+		var origins []string
+		for _, origin := range strings.Split(*httpOrigins, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				origins = append(origins, origin)
+			}
+		}
+		go serveGateway(*httpPort, pubSub, origins)
+	}
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
 	if err != nil {
 		log.Fatalf("监听端口失败: %v", err)
 	}
 
-	server := grpc.NewServer()
-	pb.RegisterPubSubServer(server, NewPubSubServer())
+	var opts []grpc.ServerOption
+	if acl != nil {
+		opts = append(opts, grpc.UnaryInterceptor(acl.unaryInterceptor), grpc.StreamInterceptor(acl.streamInterceptor))
+	}
+	server := grpc.NewServer(opts...)
+	pb.RegisterPubSubServer(server, pubSub)
 
-	log.Println("服务器运行在端口 1234")
+	log.Printf("服务器运行在端口 %d (消息代理: %s)", *port, *brokerName)
 	if err := server.Serve(lis); err != nil {
 		log.Fatalf("服务运行失败: %v", err)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"runtime"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// newTestServer 创建使用内存消息代理的服务
+func newTestServer(t *testing.T, cfg serverConfig) (*pubSubServer, *memoryBroker) {
+	t.Helper()
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = 16
+	}
+	broker := newMemoryBroker(Retention{})
+	s, err := NewPubSubServer(broker, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { broker.Close() })
+	return s, broker
+}
+
+// testStream 测试用的订阅服务端流，将 Subscribe 发送的每条响应交给 send
+type testStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	send func(*pb.SubscribeResponse) error
+}
+
+func (st *testStream) Send(resp *pb.SubscribeResponse) error { return st.send(resp) }
+func (st *testStream) Context() context.Context              { return st.ctx }
+
+// startSubscribe 在后台调用 Subscribe，每条响应交给 send，返回接收处理函数返回值的通道
+func startSubscribe(ctx context.Context, s *pubSubServer, req *pb.SubscribeRequest, send func(*pb.SubscribeResponse) error) <-chan error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Subscribe(req, &testStream{ctx: ctx, send: send})
+	}()
+	return errc
+}
+
+// waitSubscribers 等待主题在消息代理中的订阅数达到 n
+func waitSubscribers(t *testing.T, b *memoryBroker, topic string, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		got, _ := b.Subscribers(context.Background(), topic)
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("主题 %s 的订阅数为 %d，期望 %d", topic, got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// 空闲主题上的订阅在客户端取消后立即结束，反复订阅和取消不会泄漏协程
+func TestSubscribeCancelNoLeak(t *testing.T) {
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(out)
+
+	s, broker := newTestServer(t, serverConfig{HeartbeatInterval: time.Second})
+	discard := func(*pb.SubscribeResponse) error { return nil }
+
+	before := runtime.NumGoroutine()
+	for range 3000 {
+		ctx, cancel := context.WithCancel(context.Background())
+		errc := startSubscribe(ctx, s, &pb.SubscribeRequest{Topic: "idle"}, discard)
+		waitSubscribers(t, broker, "idle", 1)
+		cancel()
+		select {
+		case <-errc:
+		case <-time.After(time.Second):
+			t.Fatal("取消后订阅没有结束")
+		}
+	}
+	waitSubscribers(t, broker, "idle", 0)
+
+	// 已结束的协程可能还没有完全退出，等待一段时间再比较
+	deadline := time.Now().Add(2 * time.Second)
+	after := runtime.NumGoroutine()
+	for after > before+5 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		after = runtime.NumGoroutine()
+	}
+	if after > before+5 {
+		t.Fatalf("订阅前 %d 个协程，3000 次订阅和取消后 %d 个", before, after)
+	}
+	if n := len(s.subscriptions); n != 0 {
+		t.Errorf("仍有 %d 个订阅登记", n)
+	}
+}
+
+// 空闲的订阅流定期收到心跳
+func TestSubscribeHeartbeat(t *testing.T) {
+	s, _ := newTestServer(t, serverConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	heartbeats := make(chan struct{}, 10)
+	startSubscribe(ctx, s, &pb.SubscribeRequest{Topic: "idle", HeartbeatIntervalMs: 20}, func(resp *pb.SubscribeResponse) error {
+		if resp.Heartbeat {
+			select {
+			case heartbeats <- struct{}{}:
+			default:
+			}
+		}
+		return nil
+	})
+	for range 2 {
+		select {
+		case <-heartbeats:
+		case <-time.After(time.Second):
+			t.Fatal("空闲订阅没有收到心跳")
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// requestTopics 合并 SubscribeRequest 中的 topic 和 topics 字段并去重
+func requestTopics(req *pb.SubscribeRequest) []string {
+	seen := make(map[string]bool)
+	var topics []string
+	for _, t := range append([]string{req.Topic}, req.Topics...) {
+		if t != "" && !seen[t] {
+			seen[t] = true
+			topics = append(topics, t)
+		}
+	}
+	return topics
+}
+
+// newSubscriptionID 生成随机的订阅 ID，并发订阅不会生成相同的 ID
+func newSubscriptionID() string {
+	return "sub-" + rand.Text()
+}
+
+// newConsumerName 为未指定名称的消费者生成随机名称，同时加入消费者组的订阅者不会使用相同的名称
+func newConsumerName() string {
+	return "consumer-" + rand.Text()
+}
+
+// newMemberID 生成分区消费者组成员的随机 ID，同名消费者的多个订阅各自是独立的成员
+func newMemberID(consumer string) string {
+	return consumer + "/" + rand.Text()
+}
+
+// registeredSubscription 登记的订阅及创建它的调用方
+type registeredSubscription struct {
+	Subscription
+	principal string // 启用访问控制时创建订阅的调用方，未启用时为空
+}
+
+// registerSubscription 登记订阅并记录调用方，订阅 ID 已被占用时返回错误
+func (s *pubSubServer) registerSubscription(ctx context.Context, id string, sub Subscription) error {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	if _, ok := s.subscriptions[id]; ok {
+		return status.Errorf(codes.AlreadyExists, "订阅 %s 已存在", id)
+	}
+	s.subscriptions[id] = &registeredSubscription{Subscription: sub, principal: principalName(ctx)}
+	return nil
+}
+
+// unregisterSubscription 移除订阅登记
+func (s *pubSubServer) unregisterSubscription(id string) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	delete(s.subscriptions, id)
+}
+
+// UpdateSubscription 为已有订阅添加或移除主题，启用访问控制时只有创建订阅的调用方可以更新
+func (s *pubSubServer) UpdateSubscription(ctx context.Context, req *pb.UpdateSubscriptionRequest) (*pb.UpdateSubscriptionResponse, error) {
+	if s.durable {
+		return nil, status.Error(codes.FailedPrecondition, "持久化模式下不支持更新订阅")
+	}
+
+	s.subMu.Lock()
+	sub, ok := s.subscriptions[req.SubscriptionId]
+	s.subMu.Unlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "订阅 %s 不存在", req.SubscriptionId)
+	}
+	if caller := principalName(ctx); sub.principal != caller {
+		return nil, status.Errorf(codes.PermissionDenied, "调用方 %s 不能更新其他调用方的订阅 %s", caller, req.SubscriptionId)
+	}
+	if err := validateTopics(req.AddTopics...); err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, permSubscribe, req.AddTopics...); err != nil {
+		return nil, err
+	}
+
+	if err := sub.Add(ctx, req.AddTopics...); err != nil {
+		return nil, status.Errorf(codes.Internal, "更新订阅失败: %v", err)
+	}
+	if err := sub.Remove(ctx, req.RemoveTopics...); err != nil {
+		return nil, status.Errorf(codes.Internal, "更新订阅失败: %v", err)
+	}
+
+	topics := sub.Topics()
+	log.Printf("订阅 %s 已更新，当前主题: %v", req.SubscriptionId, topics)
+	return &pb.UpdateSubscriptionResponse{Topics: topics}, nil
+}
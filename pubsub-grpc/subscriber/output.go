@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// messageHandler 处理收到的一条消息，返回错误表示处理失败，消费者组模式下消息会被否认
+type messageHandler interface {
+	handle(ctx context.Context, msg *pb.SubscribeResponse) error
+}
+
+// outputHandler 按格式将消息写入输出
+type outputHandler struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format string
+}
+
+// newOutputHandler 创建输出处理器，format 为 plain、json 或 raw
+func newOutputHandler(w io.Writer, format string) (*outputHandler, error) {
+	switch format {
+	case "plain", "json", "raw":
+		return &outputHandler{w: w, format: format}, nil
+	default:
+		return nil, fmt.Errorf("未知的输出格式: %s", format)
+	}
+}
+
+// handle 写入一条消息，每条消息占一行（raw 格式为消息内容加换行）
+func (h *outputHandler) handle(ctx context.Context, msg *pb.SubscribeResponse) error {
+	line, err := formatMessage(msg, h.format)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err = h.w.Write(line)
+	return err
+}
+
+// formatMessage 按输出格式编码消息
+func formatMessage(msg *pb.SubscribeResponse, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case "raw":
+		return append(messagePayload(msg), '\n'), nil
+	}
+
+	matched := msg.Topic
+	if msg.Pattern != "" {
+		matched = fmt.Sprintf("%s (模式 %s)", msg.Topic, msg.Pattern)
+	}
+	kind := "消息"
+	if msg.Retained {
+		kind = "保留消息"
+	}
+	env := msg.Envelope
+	if env == nil {
+		return []byte(fmt.Sprintf("%s 从主题 %s 接收到%s: %s\n", time.Now().Format(time.RFC3339), matched, kind, msg.Message)), nil
+	}
+	return []byte(fmt.Sprintf("%s 从主题 %s 接收到%s %s (发布者 %s, 类型 %s, 消息头 %v): %s\n",
+		env.PublishTime.AsTime().Local().Format(time.RFC3339), matched, kind, env.Id, env.PublisherId,
+		env.ContentType, env.Headers, env.Payload)), nil
+}
+
+// messagePayload 返回消息内容，旧版服务端没有消息信封时使用文本字段
+func messagePayload(msg *pb.SubscribeResponse) []byte {
+	if msg.Envelope != nil {
+		return msg.Envelope.Payload
+	}
+	return []byte(msg.Message)
+}
+
+// execHandler 对每条消息执行一次 shell 命令，消息内容写入命令的标准输入，退出码为 0 时视为处理成功
+type execHandler struct {
+	command string
+	timeout time.Duration // 单条消息的处理超时，0 表示不限制
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// handle 执行命令处理消息，消息的元数据通过环境变量传给命令：
+// PUBSUB_TOPIC、PUBSUB_MESSAGE_ID、PUBSUB_PUBLISHER_ID、PUBSUB_CONTENT_TYPE、
+// PUBSUB_PUBLISH_TIME、PUBSUB_DELIVERY_ATTEMPT、PUBSUB_ORDERING_KEY、PUBSUB_PARTITION、PUBSUB_RETAINED 以及每个消息头 PUBSUB_HEADER_<名称>
+func (h *execHandler) handle(ctx context.Context, msg *pb.SubscribeResponse) error {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.command)
+	cmd.Stdin = bytes.NewReader(messagePayload(msg))
+	cmd.Stdout = h.stdout
+	cmd.Stderr = io.MultiWriter(h.stderr, &stderr)
+	cmd.Env = append(os.Environ(), messageEnv(msg)...)
+
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("命令执行超过 %v", h.timeout)
+	}
+	if detail := lastLine(stderr.String()); detail != "" {
+		return fmt.Errorf("命令执行失败: %v: %s", err, detail)
+	}
+	return fmt.Errorf("命令执行失败: %v", err)
+}
+
+// messageEnv 返回传给命令的消息元数据环境变量
+func messageEnv(msg *pb.SubscribeResponse) []string {
+	env := []string{
+		"PUBSUB_TOPIC=" + msg.Topic,
+		"PUBSUB_MESSAGE_ID=" + msg.Id,
+		"PUBSUB_DELIVERY_ATTEMPT=" + strconv.FormatInt(msg.DeliveryAttempt, 10),
+		"PUBSUB_PARTITION=" + strconv.FormatInt(int64(msg.Partition), 10),
+		"PUBSUB_RETAINED=" + strconv.FormatBool(msg.Retained),
+	}
+	if e := msg.Envelope; e != nil {
+		env = append(env,
+			"PUBSUB_PUBLISHER_ID="+e.PublisherId,
+			"PUBSUB_CONTENT_TYPE="+e.ContentType,
+			"PUBSUB_PUBLISH_TIME="+e.PublishTime.AsTime().Format(time.RFC3339Nano),
+			"PUBSUB_ORDERING_KEY="+e.OrderingKey,
+		)
+		for k, v := range e.Headers {
+			env = append(env, "PUBSUB_HEADER_"+envName(k)+"="+v)
+		}
+	}
+	return env
+}
+
+// envName 将消息头名称转换为环境变量名：大写，非字母数字字符替换为下划线
+func envName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+// lastLine 返回输出的最后一个非空行，用作失败原因
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// rotatingFile 写入文件，文件超过 maxSize 字节时轮转为 <文件>.1、<文件>.2 ...，最多保留 maxBackups 个旧文件
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// openRotatingFile 以追加方式打开输出文件
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	rf := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.file, rf.size = f, info.Size()
+	return nil
+}
+
+// Write 写入数据，写入后会超过大小上限时先轮转文件
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// rotate 关闭当前文件并依次重命名旧文件，超过保留数量的旧文件被删除
+func (rf *rotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	if rf.maxBackups <= 0 {
+		os.Remove(rf.path)
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", rf.path, rf.maxBackups))
+		for i := rf.maxBackups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", rf.path, i), fmt.Sprintf("%s.%d", rf.path, i+1))
+		}
+		if err := os.Rename(rf.path, rf.path+".1"); err != nil {
+			return err
+		}
+	}
+	return rf.open()
+}
+
+// Close 关闭当前文件
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.file.Close()
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "pubsub/proto/pubsub"
+)
+
+// subscriptionState 在重新连接之间保留的订阅状态
+type subscriptionState struct {
+	mu       sync.Mutex
+	topics   []string // 当前订阅的主题，通过标准输入命令更新
+	lastID   string   // 最后处理成功的消息 ID，重新订阅时从它之后继续
+	alive    bool     // 本次连接是否收到过消息或心跳
+	noResume bool     // 服务端不支持回放，重新订阅时从最新消息开始
+}
+
+// setTopics 记录服务端返回的当前订阅主题
+func (st *subscriptionState) setTopics(topics []string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.topics = append([]string(nil), topics...)
+}
+
+// received 记录本次连接收到了消息或心跳
+func (st *subscriptionState) received() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.alive = true
+}
+
+// handled 记录处理成功的消息，重新订阅时从它之后继续
+func (st *subscriptionState) handled(id string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if id != "" {
+		st.lastID = id
+	}
+}
+
+// request 根据订阅状态生成本次连接的订阅请求，返回是否从最后处理成功的消息之后继续
+func (st *subscriptionState) request(base *pb.SubscribeRequest) (*pb.SubscribeRequest, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.alive = false
+
+	req := proto.Clone(base).(*pb.SubscribeRequest)
+	req.Topic = ""
+	req.Topics = append([]string(nil), st.topics...)
+	// 消费者组的进度由服务端记录，无需指定起始位置
+	if req.Group != "" || st.lastID == "" || st.noResume {
+		return req, false
+	}
+	req.StartPosition = pb.StartPosition_START_AFTER_ID
+	req.StartId = st.lastID
+	req.StartTime = nil
+	req.LastN = 0
+	return req, true
+}
+
+// backoff 带随机抖动的指数退避
+type backoff struct {
+	min, max time.Duration
+	attempt  int
+}
+
+// next 返回下一次重试前的等待时间，在 [d/2, d) 之间随机，d 每次翻倍直到 max
+func (b *backoff) next() time.Duration {
+	d := b.min << b.attempt
+	if d <= 0 || d > b.max {
+		d = b.max
+	} else {
+		b.attempt++
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// reset 连接恢复后重新从最短等待时间开始
+func (b *backoff) reset() {
+	b.attempt = 0
+}
+
+// permanentError 判断订阅错误是否无法通过重新连接恢复
+func permanentError(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.FailedPrecondition, codes.PermissionDenied,
+		codes.Unauthenticated, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+// reconnectPolicy 重新连接的参数
+type reconnectPolicy struct {
+	enabled  bool
+	min, max time.Duration
+}
+
+// runSubscriber 订阅主题并处理消息，连接断开后按指数退避重新连接，
+// 重新订阅当前的全部主题并从最后处理成功的消息之后继续
+func runSubscriber(ctx context.Context, client pb.PubSubClient, base *pb.SubscribeRequest, state *subscriptionState, opts consumeOptions, policy reconnectPolicy) error {
+	bo := &backoff{min: policy.min, max: policy.max}
+	for reconnects := 0; ; {
+		req, resumed := state.request(base)
+		if reconnects > 0 {
+			if resumed {
+				log.Printf("第 %d 次重新订阅主题 %v，从消息 %s 之后继续", reconnects, req.Topics, req.StartId)
+			} else {
+				log.Printf("第 %d 次重新订阅主题 %v", reconnects, req.Topics)
+			}
+		}
+
+		err := subscribeToTopics(client, req, state, opts, ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			if !policy.enabled {
+				return nil
+			}
+			err = errors.New("服务端结束了订阅")
+		}
+
+		// 服务端不支持回放历史消息时改为从最新消息开始，不计入重连次数
+		if resumed && status.Code(err) == codes.Unimplemented {
+			log.Printf("服务端不支持从指定消息继续，改为从最新消息开始订阅: %v", err)
+			state.mu.Lock()
+			state.noResume = true
+			state.mu.Unlock()
+			continue
+		}
+		if !policy.enabled || permanentError(err) {
+			return err
+		}
+
+		state.mu.Lock()
+		alive := state.alive
+		state.mu.Unlock()
+		if alive {
+			bo.reset()
+		}
+		delay := bo.next()
+		reconnects++
+		log.Printf("订阅已断开: %v，%v 后重新连接", err, delay.Round(time.Millisecond))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
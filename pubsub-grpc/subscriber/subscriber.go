@@ -1,49 +1,273 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"pubsub/auth"
 	pb "pubsub/proto/pubsub"
 )
 
-func subscribeToTopic(client pb.PubSubClient, topic string, ctx context.Context) error {
-	log.Printf("开始订阅主题: %s", topic)
+// parseStart 解析 -start 参数：latest、earliest、id:<消息ID>、time:<RFC3339时间>、last:<N>
+func parseStart(start string, req *pb.SubscribeRequest) error {
+	kind, value, _ := strings.Cut(start, ":")
+	switch kind {
+	case "", "latest":
+		req.StartPosition = pb.StartPosition_START_LATEST
+	case "earliest":
+		req.StartPosition = pb.StartPosition_START_EARLIEST
+	case "id":
+		req.StartPosition = pb.StartPosition_START_AFTER_ID
+		req.StartId = value
+	case "time":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("无效的起始时间 %q: %v", value, err)
+		}
+		req.StartPosition = pb.StartPosition_START_FROM_TIME
+		req.StartTime = timestamppb.New(t)
+	case "last":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("无效的消息数量 %q", value)
+		}
+		req.StartPosition = pb.StartPosition_START_LAST_N
+		req.LastN = int32(n)
+	default:
+		return fmt.Errorf("未知的起始位置 %q", start)
+	}
+	return nil
+}
+
+// defaultOffsetFile 根据订阅的主题生成默认的消费进度文件名
+func defaultOffsetFile(topic string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '*' || r == '?' || r == ',' || r == '[' || r == ']' {
+			return '_'
+		}
+		return r
+	}, topic)
+	return ".subscriber-" + name + ".offset"
+}
+
+// loadOffset 读取上次处理的消息 ID，文件不存在时返回空字符串
+func loadOffset(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// saveOffset 原子地写入最后处理的消息 ID
+func saveOffset(path, id string) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(id+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// parseOverflow 解析缓冲区溢出策略，空字符串表示使用服务端默认策略
+func parseOverflow(name string) (pb.OverflowPolicy, error) {
+	switch name {
+	case "":
+		return pb.OverflowPolicy_OVERFLOW_DEFAULT, nil
+	case "block":
+		return pb.OverflowPolicy_OVERFLOW_BLOCK, nil
+	case "drop-oldest":
+		return pb.OverflowPolicy_OVERFLOW_DROP_OLDEST, nil
+	case "drop-newest":
+		return pb.OverflowPolicy_OVERFLOW_DROP_NEWEST, nil
+	case "disconnect":
+		return pb.OverflowPolicy_OVERFLOW_DISCONNECT, nil
+	default:
+		return 0, fmt.Errorf("未知的溢出策略: %s", name)
+	}
+}
+
+// consumeOptions 订阅者处理消息的参数
+type consumeOptions struct {
+	handler          messageHandler
+	offsetFile       string        // 消费进度文件，为空时不记录进度
+	nackMatch        string        // 内容包含该字符串的消息按处理失败处理
+	delay            time.Duration // 每条消息额外的处理耗时
+	heartbeatTimeout time.Duration // 超过该时间未收到消息或心跳时断开
+}
+
+// subscribeToTopics 建立一次订阅并处理收到的消息，直到连接断开或 ctx 取消
+func subscribeToTopics(client pb.PubSubClient, req *pb.SubscribeRequest, state *subscriptionState, opts consumeOptions, ctx context.Context) error {
+	topics, group := req.Topics, req.Group
+	heartbeatTimeout := opts.heartbeatTimeout
+	var dropped uint64
+	log.Printf("开始订阅主题: %v (起始位置 %v)", topics, req.StartPosition)
 
-	stream, err := client.Subscribe(ctx, &pb.SubscribeRequest{
-		Topic: topic,
-	})
+	// 超过 heartbeatTimeout 未收到消息或心跳时认为连接已断开
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	defer cancelStream()
+	var idle *time.Timer
+	if heartbeatTimeout > 0 {
+		idle = time.AfterFunc(heartbeatTimeout, cancelStream)
+		defer idle.Stop()
+	}
+
+	stream, err := client.Subscribe(streamCtx, req)
 	if err != nil {
-		return fmt.Errorf("无法订阅主题 %s: %v", topic, err)
+		return fmt.Errorf("无法订阅主题 %v: %w", topics, err)
 	}
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("取消订阅主题 %s", topic)
+			log.Printf("取消订阅主题 %v", topics)
 			return nil
 		default:
 			msg, err := stream.Recv()
 			if err != nil {
-				return fmt.Errorf("从主题 %s 接收消息时出错: %v", topic, err)
+				if ctx.Err() != nil {
+					log.Printf("取消订阅主题 %v", topics)
+					return nil
+				}
+				if streamCtx.Err() != nil {
+					return fmt.Errorf("超过 %v 未收到消息或心跳，连接可能已断开", heartbeatTimeout)
+				}
+				return fmt.Errorf("从主题 %v 接收消息时出错: %w", topics, err)
+			}
+			if idle != nil {
+				idle.Reset(heartbeatTimeout)
+			}
+			state.received()
+			if msg.Heartbeat {
+				continue
+			}
+			if msg.DroppedCount > dropped {
+				log.Printf("处理过慢，服务端已丢弃 %d 条消息 (累计 %d 条)", msg.DroppedCount-dropped, msg.DroppedCount)
+				dropped = msg.DroppedCount
+			}
+
+			// 模拟较慢的消息处理
+			if opts.delay > 0 {
+				time.Sleep(opts.delay)
+			}
+
+			// 处理消息，内容包含 nackMatch 的消息模拟处理失败
+			err = opts.handler.handle(ctx, msg)
+			if err == nil && opts.nackMatch != "" && strings.Contains(string(messagePayload(msg)), opts.nackMatch) {
+				err = fmt.Errorf("消息内容包含 %q", opts.nackMatch)
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					continue
+				}
+				log.Printf("处理消息 %s 失败 (第 %d 次投递): %v", msg.Id, msg.DeliveryAttempt, err)
+				// 消费者组模式下否认消息，使其重新投递
+				if group != "" && msg.Id != "" {
+					_, err := client.Nack(ctx, &pb.NackRequest{Topic: msg.Topic, Group: group, Ids: []string{msg.Id}, Error: err.Error()})
+					if err != nil {
+						log.Printf("否认消息 %s 失败: %v", msg.Id, err)
+					}
+				}
+				continue
 			}
-			log.Printf("从主题 %s 接收到消息: %s", topic, msg.Message)
+
+			// 处理成功后才记录消费进度，重新连接或重启后从该消息之后继续。
+			// 保留消息是主题中较早的消息，不作为进度，否则会回放它之后已处理过的消息
+			if !msg.Retained {
+				state.handled(msg.Id)
+				if opts.offsetFile != "" && msg.Id != "" {
+					if err := saveOffset(opts.offsetFile, msg.Id); err != nil {
+						log.Printf("保存消费进度失败: %v", err)
+					}
+				}
+			}
+
+			// 消费者组模式下处理成功后确认消息
+			if group != "" && msg.Id != "" {
+				_, err := client.Ack(ctx, &pb.AckRequest{Topic: msg.Topic, Group: group, Ids: []string{msg.Id}})
+				if err != nil {
+					log.Printf("确认消息 %s 失败: %v", msg.Id, err)
+				}
+			}
+		}
+	}
+}
+
+// readCommands 从标准输入读取命令更新订阅："+主题" 添加主题，"-主题" 移除主题
+func readCommands(client pb.PubSubClient, subscriptionID string, state *subscriptionState, ctx context.Context) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 2 {
+			continue
+		}
+
+		req := &pb.UpdateSubscriptionRequest{SubscriptionId: subscriptionID}
+		switch line[0] {
+		case '+':
+			req.AddTopics = []string{line[1:]}
+		case '-':
+			req.RemoveTopics = []string{line[1:]}
+		default:
+			log.Printf("未知命令 %q，使用 +主题 添加订阅，-主题 移除订阅", line)
+			continue
+		}
+
+		resp, err := client.UpdateSubscription(ctx, req)
+		if err != nil {
+			log.Printf("更新订阅失败: %v", err)
+			continue
 		}
+		state.setTopics(resp.Topics)
+		log.Printf("当前订阅的主题: %v", resp.Topics)
 	}
 }
 
 func main() {
 	// 定义命令行参数
-	var topic string
-	flag.StringVar(&topic, "topic", "", "要订阅的主题名称 (必需)")
+	var addr, token, topic, group, consumer, start, offsetFile, overflow, deadLetter, nackMatch, filter string
+	var output, outFile, execCommand string
+	var resume, reconnect bool
+	var bufferSize, maxAttempts, outMaxSize, outMaxFiles int
+	var delay, heartbeatTimeout, execTimeout, reconnectMin, reconnectMax time.Duration
+	flag.StringVar(&addr, "addr", "localhost:1234", "PubSub 服务地址")
+	flag.StringVar(&token, "token", os.Getenv("PUBSUB_TOKEN"), "访问令牌，服务端启用 ACL 时需要，默认读取环境变量 PUBSUB_TOKEN")
+	flag.StringVar(&topic, "topic", "", "要订阅的主题名称，多个主题用逗号分隔，支持 glob 模式如 orders.* (必需)")
+	flag.StringVar(&group, "group", "", "消费者组名称 (仅服务端持久化模式)")
+	flag.StringVar(&consumer, "consumer", "", "消费者名称，默认由服务端生成")
+	flag.IntVar(&maxAttempts, "max-attempts", 0, "消费者组中每条消息的最大投递次数，超过后移入死信主题，0 表示使用服务端配置")
+	flag.StringVar(&deadLetter, "dead-letter", "", "死信主题，默认使用服务端配置的 <主题>.dlq")
+	flag.StringVar(&nackMatch, "nack-match", "", "消费者组中内容包含该字符串的消息按处理失败否认，用于模拟无法处理的消息")
+	flag.StringVar(&filter, "filter", "", `消息头过滤表达式，如 'region = "eu" AND priority >= 5'，只接收满足条件的消息`)
+	flag.StringVar(&start, "start", "latest", "起始位置: latest、earliest、id:<消息ID>、time:<RFC3339时间>、last:<N>")
+	flag.BoolVar(&resume, "resume", true, "从消费进度文件中记录的消息之后继续订阅 (消费者组模式下由服务端记录进度)")
+	flag.StringVar(&offsetFile, "offset-file", "", "消费进度文件，默认根据主题生成")
+	flag.IntVar(&bufferSize, "buffer", 0, "服务端为该订阅保留的消息缓冲区大小，0 表示使用服务端默认值")
+	flag.StringVar(&overflow, "overflow", "", "缓冲区满时的处理策略: block、drop-oldest、drop-newest 或 disconnect，默认使用服务端配置")
+	flag.DurationVar(&delay, "delay", 0, "每条消息的处理耗时，用于模拟慢速订阅者")
+	flag.DurationVar(&heartbeatTimeout, "heartbeat-timeout", 45*time.Second, "超过该时间未收到消息或心跳时断开订阅，应大于服务端心跳间隔，0 表示不检测")
+	flag.StringVar(&output, "output", "plain", "消息输出格式: plain 可读文本、json 每行一个 JSON（包含完整的消息信封）、raw 只输出消息内容")
+	flag.StringVar(&outFile, "out-file", "", "消息输出文件，默认输出到标准输出")
+	flag.IntVar(&outMaxSize, "out-max-size", 100, "输出文件的最大大小 (MB)，超过后轮转，0 表示不轮转")
+	flag.IntVar(&outMaxFiles, "out-max-files", 5, "轮转后保留的旧输出文件数量")
+	flag.StringVar(&execCommand, "exec", "", "对每条消息执行的 shell 命令，消息内容写入标准输入，退出码为 0 时才确认消息")
+	flag.DurationVar(&execTimeout, "exec-timeout", 0, "-exec 命令处理单条消息的超时时间，0 表示不限制")
+	flag.BoolVar(&reconnect, "reconnect", true, "连接断开后自动重新连接并重新订阅")
+	flag.DurationVar(&reconnectMin, "reconnect-min", 500*time.Millisecond, "重新连接的最短等待时间，之后每次翻倍")
+	flag.DurationVar(&reconnectMax, "reconnect-max", 30*time.Second, "重新连接的最长等待时间")
 	flag.Parse()
 
 	// 检查是否提供了主题参数
@@ -51,6 +275,7 @@ func main() {
 		fmt.Println("错误: 必须指定要订阅的主题")
 		fmt.Println("用法: subscriber -topic=<主题名称>")
 		fmt.Println("示例: subscriber -topic=topic1")
+		fmt.Println("示例: subscriber -topic=topic1,orders.*")
 		os.Exit(1)
 	}
 
@@ -67,7 +292,36 @@ func main() {
 		cancel()
 	}()
 
-	conn, err := grpc.NewClient("localhost:1234", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if reconnectMin <= 0 || reconnectMax < reconnectMin {
+		log.Fatalf("参数错误: -reconnect-min 必须大于 0 且不大于 -reconnect-max")
+	}
+
+	// 消息输出到标准输出或轮转文件，日志输出到标准错误
+	var out io.Writer = os.Stdout
+	if outFile != "" {
+		rf, err := openRotatingFile(outFile, int64(outMaxSize)<<20, outMaxFiles)
+		if err != nil {
+			log.Fatalf("打开输出文件失败: %v", err)
+		}
+		defer rf.Close()
+		out = rf
+	}
+	var handler messageHandler
+	if execCommand != "" {
+		handler = &execHandler{command: execCommand, timeout: execTimeout, stdout: out, stderr: os.Stderr}
+	} else {
+		h, err := newOutputHandler(out, output)
+		if err != nil {
+			log.Fatalf("参数错误: %v", err)
+		}
+		handler = h
+	}
+
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(auth.TokenCredentials(token)))
+	}
+	conn, err := grpc.NewClient(addr, dialOpts...)
 	if err != nil {
 		log.Fatalf("连接失败: %v", err)
 	}
@@ -75,8 +329,54 @@ func main() {
 
 	client := pb.NewPubSubClient(conn)
 
+	// 非消费者组模式下支持通过标准输入动态添加或移除主题
+	subscriptionID := fmt.Sprintf("subscriber-%d-%d", os.Getpid(), time.Now().UnixNano())
+	state := &subscriptionState{topics: strings.Split(topic, ",")}
+	if group == "" {
+		go readCommands(client, subscriptionID, state, ctx)
+	}
+
+	req := &pb.SubscribeRequest{
+		Topics:              strings.Split(topic, ","),
+		Group:               group,
+		Consumer:            consumer,
+		SubscriptionId:      subscriptionID,
+		BufferSize:          int32(bufferSize),
+		MaxDeliveryAttempts: int32(maxAttempts),
+		DeadLetterTopic:     deadLetter,
+		Filter:              filter,
+	}
+	if err := parseStart(start, req); err != nil {
+		log.Fatalf("参数错误: %v", err)
+	}
+	if req.OverflowPolicy, err = parseOverflow(overflow); err != nil {
+		log.Fatalf("参数错误: %v", err)
+	}
+
+	// 非消费者组模式下记录消费进度，重启后自动续订
+	if group == "" {
+		if offsetFile == "" {
+			offsetFile = defaultOffsetFile(topic)
+		}
+		if last := loadOffset(offsetFile); resume && last != "" {
+			log.Printf("从消息 %s 之后继续订阅 (进度文件 %s)", last, offsetFile)
+			req.StartPosition = pb.StartPosition_START_AFTER_ID
+			req.StartId = last
+		}
+	} else {
+		offsetFile = ""
+	}
+
 	// 订阅指定的主题
-	if err := subscribeToTopic(client, topic, ctx); err != nil {
+	opts := consumeOptions{
+		handler:          handler,
+		offsetFile:       offsetFile,
+		nackMatch:        nackMatch,
+		delay:            delay,
+		heartbeatTimeout: heartbeatTimeout,
+	}
+	policy := reconnectPolicy{enabled: reconnect, min: reconnectMin, max: reconnectMax}
+	if err := runSubscriber(ctx, client, req, state, opts, policy); err != nil {
 		log.Fatalf("订阅失败: %v", err)
 	}
 